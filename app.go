@@ -4,9 +4,7 @@ import (
 	"context"
 	"fmt"
 	"imagetoexcel/internal/engine"
-	"os"
 	"os/exec"
-	"path/filepath"
 	"strings"
 
 	stdruntime "runtime"
@@ -40,14 +38,261 @@ type Config struct {
 	RowHeight   float64 `json:"rowHeight"`
 	ColWidth    float64 `json:"colWidth"`
 	WorkerCount int     `json:"workerCount"`
+
+	// Several sheets in one run (SheetName is used when both are empty)
+	Sheets    []engine.SheetTarget `json:"sheets"`
+	AllSheets bool                 `json:"allSheets"`
+
+	// Columns by header text (override CodeCol/ImageCol when set)
+	CodeHeader  string `json:"codeHeader"`
+	ImageHeader string `json:"imageHeader"`
+	HeaderRow   int    `json:"headerRow"`
+
+	// Column holding an image file, absolute path or glob per row; other rows match by code
+	PathCol string `json:"pathCol"`
+
+	// Product code matching
+	MatchIgnoreCase      bool   `json:"matchIgnoreCase"`
+	MatchFoldDiacritics  bool   `json:"matchFoldDiacritics"`
+	MatchStripSeparators bool   `json:"matchStripSeparators"`
+	MatchPadZeros        int    `json:"matchPadZeros"`
+	MatchPattern         string `json:"matchPattern"`
+
+	// Image folder scanning (lists are comma separated)
+	ScanRecursive      bool   `json:"scanRecursive"`
+	ScanMaxDepth       int    `json:"scanMaxDepth"`
+	ScanInclude        string `json:"scanInclude"`
+	ScanExclude        string `json:"scanExclude"`
+	ScanSymlinks       string `json:"scanSymlinks"`
+	ScanFolderPriority string `json:"scanFolderPriority"`
+
+	// Several images per code (e.g. P001_2.jpg)
+	VariantSuffix string `json:"variantSuffix"`
+	VariantMax    int    `json:"variantMax"`
+	VariantLayout string `json:"variantLayout"`
+	VariantOrder  string `json:"variantOrder"`
+
+	// Same code with several extensions (e.g. P001.png and P001.jpg)
+	ConflictPolicy string `json:"conflictPolicy"`
+	ExtensionOrder string `json:"extensionOrder"`
+
+	// Downscaling and recompression before embedding
+	KeepOriginal bool    `json:"keepOriginal"`
+	DPIScale     float64 `json:"dpiScale"`
+	ImageQuality int     `json:"imageQuality"`
+	ImageFormat  string  `json:"imageFormat"`
+
+	// Trim borders and pad to the cell aspect ratio
+	Trim          bool   `json:"trim"`
+	TrimTolerance *int   `json:"trimTolerance,omitempty"`
+	Pad           bool   `json:"pad"`
+	Background    string `json:"background"`
+
+	// On-disk cache of processed images, disabled when CacheDir is empty
+	CacheDir   string `json:"cacheDir"`
+	CacheMaxMB int    `json:"cacheMaxMb"`
+
+	// Target application; formats it cannot display are transcoded
+	Compatibility string `json:"compatibility"`
+
+	// Leave CMYK and wide-gamut images unconverted instead of converting them to sRGB
+	KeepColors bool `json:"keepColors"`
+
+	// Picture alignment and margins inside the cell (margins in pixels, unset for the default)
+	// and anchoring: oneCell, twoCell (move and size with cells) or absolute
+	Align       string `json:"align"`
+	MarginX     *int   `json:"marginX,omitempty"`
+	MarginY     *int   `json:"marginY,omitempty"`
+	Positioning string `json:"positioning"`
+
+	// Picture name, alt text and hyperlink templates ({code}, {file}, {sheet}, {row}, {col:X});
+	// PictureLink is "file" or a URL template
+	PictureName     string `json:"pictureName"`
+	PictureAltText  string `json:"pictureAltText"`
+	PictureLink     string `json:"pictureLink"`
+	LockAspectRatio bool   `json:"lockAspectRatio"`
+	UnlockPictures  bool   `json:"unlockPictures"`
+	NoPrintPictures bool   `json:"noPrintPictures"`
+
+	// Row heights: fixed or fitted to each picture (bounds in points), and unmatched rows
+	RowHeightMode string   `json:"rowHeightMode"`
+	MinRowHeight  *float64 `json:"minRowHeight,omitempty"`
+	MaxRowHeight  *float64 `json:"maxRowHeight,omitempty"`
+	UnmatchedRows string   `json:"unmatchedRows"`
+
+	// Pictures already in the image cells when re-running: add, skip, replace or clear
+	ExistingPictures string `json:"existingPictures"`
+
+	// Columns filled per row with the outcome and image metadata (empty = not written)
+	StatusCol        string `json:"statusCol"`
+	FileCol          string `json:"fileCol"`
+	DimsCol          string `json:"dimsCol"`
+	SizeCol          string `json:"sizeCol"`
+	LinkCol          string `json:"linkCol"`
+	HighlightMissing bool   `json:"highlightMissing"`
+
+	// Validation of image files; rejected files are listed in QuarantineDir
+	ValidateFullDecode bool   `json:"validateFullDecode"`
+	MaxFileMB          int    `json:"maxFileMb"`
+	MaxPixels          int    `json:"maxPixels"`
+	QuarantineDir      string `json:"quarantineDir"`
+	QuarantineMove     bool   `json:"quarantineMove"`
 }
 
 // ProcessResult holds the result of processing
 type ProcessResult struct {
-	Success      bool     `json:"success"`
-	Message      string   `json:"message"`
-	MissingCodes []string `json:"missingCodes"`
-	OutputPath   string   `json:"outputPath"`
+	Success       bool                 `json:"success"`
+	Message       string               `json:"message"`
+	MissingCodes  []string             `json:"missingCodes"`
+	Matches       []engine.Match       `json:"matches"`
+	Collisions    []engine.Collision   `json:"collisions"`
+	Duplicates    []engine.Duplicate   `json:"duplicates"`
+	Conflicts     []engine.Conflict    `json:"conflicts"`
+	SheetStats    []engine.SheetStat   `json:"sheetStats"`
+	OutputPath    string               `json:"outputPath"`
+	Report        *engine.Report       `json:"report"`
+	ReportPath    string               `json:"reportPath"`
+	ReportCSVPath string               `json:"reportCsvPath"`
+	Quarantined   []engine.Quarantined `json:"quarantined"`
+}
+
+// PreviewResult holds the outcome of a dry run
+type PreviewResult struct {
+	Success      bool                `json:"success"`
+	Message      string              `json:"message"`
+	Rows         []engine.PreviewRow `json:"rows"`
+	Orphans      []string            `json:"orphans"`
+	MissingCodes []string            `json:"missingCodes"`
+	Collisions   []engine.Collision  `json:"collisions"`
+	Duplicates   []engine.Duplicate  `json:"duplicates"`
+	Conflicts    []engine.Conflict   `json:"conflicts"`
+	SheetStats   []engine.SheetStat  `json:"sheetStats"`
+}
+
+// applyDefaults fills in the settings the user left empty
+func (c *Config) applyDefaults() {
+	if c.CodeCol == "" {
+		c.CodeCol = "A"
+	}
+	if c.ImageCol == "" {
+		c.ImageCol = "F"
+	}
+	if c.RowHeight <= 0 {
+		c.RowHeight = 105
+	}
+	if c.ColWidth <= 0 {
+		c.ColWidth = 20
+	}
+	if c.WorkerCount <= 0 {
+		c.WorkerCount = 10
+	}
+}
+
+// newProcessor applies defaults and creates the engine processor for this
+// configuration. It is shared by the GUI and the command line mode so both
+// produce identical workbooks.
+func (c Config) newProcessor() *engine.Processor {
+	c.applyDefaults()
+	p := engine.NewProcessor(
+		c.ExcelPath,
+		c.ImageDir,
+		c.CodeCol,
+		c.ImageCol,
+		c.SheetName,
+		c.WorkerCount,
+		c.RowHeight,
+		c.ColWidth,
+	)
+	p.Sheets = c.Sheets
+	p.AllSheets = c.AllSheets
+	p.CodeHeader = c.CodeHeader
+	p.ImageHeader = c.ImageHeader
+	p.HeaderRow = c.HeaderRow
+	p.PathCol = c.PathCol
+	p.Match = engine.MatchOptions{
+		IgnoreCase:      c.MatchIgnoreCase,
+		FoldDiacritics:  c.MatchFoldDiacritics,
+		StripSeparators: c.MatchStripSeparators,
+		PadZeros:        c.MatchPadZeros,
+		Pattern:         c.MatchPattern,
+	}
+	p.Scan = engine.ScanOptions{
+		Recursive:      c.ScanRecursive,
+		MaxDepth:       c.ScanMaxDepth,
+		Include:        splitList(c.ScanInclude),
+		Exclude:        splitList(c.ScanExclude),
+		Symlinks:       c.ScanSymlinks,
+		FolderPriority: splitList(c.ScanFolderPriority),
+	}
+	p.Variants = engine.VariantOptions{
+		Suffix:    c.VariantSuffix,
+		MaxImages: c.VariantMax,
+		Layout:    c.VariantLayout,
+		Order:     c.VariantOrder,
+	}
+	p.ConflictPolicy = c.ConflictPolicy
+	p.ExtensionOrder = splitList(c.ExtensionOrder)
+	p.Resample = engine.ResampleOptions{
+		KeepOriginal:  c.KeepOriginal,
+		DPIScale:      c.DPIScale,
+		Quality:       c.ImageQuality,
+		Format:        c.ImageFormat,
+		Trim:          c.Trim,
+		TrimTolerance: c.TrimTolerance,
+		Pad:           c.Pad,
+		Background:    c.Background,
+		KeepColors:    c.KeepColors,
+	}
+	p.Compatibility = c.Compatibility
+	p.Cache = engine.CacheOptions{Dir: c.CacheDir, MaxBytes: int64(c.CacheMaxMB) << 20}
+	p.MaxPixels = c.MaxPixels
+	p.Placement = engine.PlacementOptions{
+		Align:       c.Align,
+		MarginX:     c.MarginX,
+		MarginY:     c.MarginY,
+		Positioning: c.Positioning,
+	}
+	p.Pictures = engine.PictureOptions{
+		Name:            c.PictureName,
+		AltText:         c.PictureAltText,
+		Link:            c.PictureLink,
+		LockAspectRatio: c.LockAspectRatio,
+		Unlocked:        c.UnlockPictures,
+		NoPrint:         c.NoPrintPictures,
+	}
+	p.Rows = engine.RowOptions{
+		Mode:      c.RowHeightMode,
+		MinHeight: c.MinRowHeight,
+		MaxHeight: c.MaxRowHeight,
+		Unmatched: c.UnmatchedRows,
+	}
+	p.Existing = c.ExistingPictures
+	p.Info = engine.InfoColumns{
+		Status:           c.StatusCol,
+		File:             c.FileCol,
+		Dims:             c.DimsCol,
+		Size:             c.SizeCol,
+		Link:             c.LinkCol,
+		HighlightMissing: c.HighlightMissing,
+	}
+	p.Validate = engine.ValidateOptions{
+		FullDecode:     c.ValidateFullDecode,
+		MaxFileBytes:   int64(c.MaxFileMB) << 20,
+		QuarantineDir:  c.QuarantineDir,
+		QuarantineMove: c.QuarantineMove,
+	}
+	return p
+}
+
+// splitList splits a comma separated setting, dropping empty items
+func splitList(s string) []string {
+	var items []string
+	for _, item := range strings.Split(s, ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
 }
 
 // SelectExcelFile opens a file dialog to select an Excel file
@@ -97,34 +342,7 @@ func (a *App) Process(config Config) ProcessResult {
 		return ProcessResult{Success: false, Message: "Please select an image folder"}
 	}
 
-	// Defaults
-	if config.CodeCol == "" {
-		config.CodeCol = "A"
-	}
-	if config.ImageCol == "" {
-		config.ImageCol = "F"
-	}
-	if config.RowHeight <= 0 {
-		config.RowHeight = 105
-	}
-	if config.ColWidth <= 0 {
-		config.ColWidth = 20
-	}
-	if config.WorkerCount <= 0 {
-		config.WorkerCount = 10
-	}
-
-	// Create processor
-	p := engine.NewProcessor(
-		config.ExcelPath,
-		config.ImageDir,
-		config.CodeCol,
-		config.ImageCol,
-		config.SheetName,
-		config.WorkerCount,
-		config.RowHeight,
-		config.ColWidth,
-	)
+	p := config.newProcessor()
 
 	// Progress channel for real-time updates
 	progressChan := make(chan float64, 100)
@@ -146,35 +364,53 @@ func (a *App) Process(config Config) ProcessResult {
 		}
 	}
 
-	// Get output file path
-	outputPath := findOutputFile(config.ExcelPath)
-
+	totals := p.Report.Totals
 	return ProcessResult{
-		Success:      true,
-		Message:      fmt.Sprintf("Processing completed! %d images processed, %d missing", p.ProcessedCount, len(p.MissingCodes)),
-		MissingCodes: p.MissingCodes,
-		OutputPath:   outputPath,
+		Success:       true,
+		Message:       fmt.Sprintf("Processing completed! %d images processed, %d missing, %d replaced, %d deduplicated, %.1f MB saved", p.ProcessedCount, len(p.MissingCodes), p.ReplacedCount, totals.Deduplicated, float64(totals.SavedBytes)/(1<<20)),
+		MissingCodes:  p.MissingCodes,
+		Matches:       p.Matches,
+		Collisions:    p.Collisions,
+		Duplicates:    p.Duplicates,
+		Conflicts:     p.Conflicts,
+		SheetStats:    p.SheetStats,
+		OutputPath:    p.OutputPath,
+		Report:        p.Report,
+		ReportPath:    p.ReportPath,
+		ReportCSVPath: p.ReportCSVPath,
+		Quarantined:   p.Quarantined,
 	}
 }
 
-// findOutputFile finds the most recent output file
-func findOutputFile(excelPath string) string {
-	dir := filepath.Dir(excelPath)
-	base := strings.TrimSuffix(filepath.Base(excelPath), filepath.Ext(excelPath))
-
-	files, err := os.ReadDir(dir)
-	if err != nil {
-		return ""
+// Preview reports what Process would do without modifying or saving the workbook
+func (a *App) Preview(config Config) PreviewResult {
+	if config.ExcelPath == "" {
+		return PreviewResult{Success: false, Message: "Please select an Excel file"}
+	}
+	if config.ImageDir == "" {
+		return PreviewResult{Success: false, Message: "Please select an image folder"}
 	}
 
-	var latestFile string
-	for _, f := range files {
-		if strings.HasPrefix(f.Name(), base+"_output_") && strings.HasSuffix(f.Name(), ".xlsx") {
-			latestFile = filepath.Join(dir, f.Name())
+	p := config.newProcessor()
+	preview, err := p.Preview(a.ctx)
+	if err != nil {
+		return PreviewResult{
+			Success: false,
+			Message: fmt.Sprintf("Preview failed: %v", err),
 		}
 	}
 
-	return latestFile
+	return PreviewResult{
+		Success:      true,
+		Message:      fmt.Sprintf("%d rows, %d codes matched, %d missing, %d unused images", len(preview.Rows), len(p.Matches), len(p.MissingCodes), len(preview.Orphans)),
+		Rows:         preview.Rows,
+		Orphans:      preview.Orphans,
+		MissingCodes: p.MissingCodes,
+		Collisions:   p.Collisions,
+		Duplicates:   p.Duplicates,
+		Conflicts:    p.Conflicts,
+		SheetStats:   p.SheetStats,
+	}
 }
 
 // OpenFileLocation opens the file explorer to the output file location
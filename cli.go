@@ -0,0 +1,237 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+
+	"imagetoexcel/internal/engine"
+)
+
+// CLICommand is the first argument that switches the binary into headless mode
+const CLICommand = "import"
+
+// Exit codes returned by the command line mode
+const (
+	ExitOK      = 0 // Workbook written
+	ExitFailure = 1 // Processing failed, no workbook written
+	ExitUsage   = 2 // Invalid or missing arguments
+	ExitMissing = 3 // Workbook written but codes were missing (only with --fail-on-missing)
+)
+
+// cliOptions holds the parsed command line arguments
+type cliOptions struct {
+	Config        Config
+	FailOnMissing bool
+	Quiet         bool
+}
+
+// parseCLIArgs parses the arguments following the import command into a Config.
+// Every field of Config has a matching flag.
+func parseCLIArgs(args []string, stderr io.Writer) (cliOptions, error) {
+	var opts cliOptions
+	c := &opts.Config
+
+	fs := flag.NewFlagSet(CLICommand, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: tool_chen_anh %s --excel <file.xlsx> --images <dir> [options]\n\nOptions:\n", CLICommand)
+		fs.PrintDefaults()
+	}
+
+	fs.StringVar(&c.ExcelPath, "excel", "", "path of the source Excel workbook (required)")
+	fs.StringVar(&c.ImageDir, "images", "", "directory containing the product images (required)")
+	fs.StringVar(&c.CodeCol, "code-col", "A", "column holding the product codes")
+	fs.StringVar(&c.ImageCol, "image-col", "F", "column where images are inserted")
+	fs.StringVar(&c.SheetName, "sheet", "", "sheet to process (default: first sheet)")
+	sheets := fs.String("sheets", "", "comma separated sheets to process, each as Name[:codeCol[:imageCol]]")
+	fs.BoolVar(&c.AllSheets, "all-sheets", false, "process every worksheet, skipping chart sheets (--sheets entries still override columns)")
+	fs.Float64Var(&c.RowHeight, "row-height", 105, "row height in points for rows with an image")
+	fs.Float64Var(&c.ColWidth, "col-width", 20, "width of the image column in characters")
+	fs.IntVar(&c.WorkerCount, "workers", 10, "number of parallel image workers")
+	fs.StringVar(&c.CodeHeader, "code-header", "", "header text of the code column (overrides --code-col)")
+	fs.StringVar(&c.ImageHeader, "image-header", "", "header text of the image column (overrides --image-col, created if missing)")
+	fs.StringVar(&c.PathCol, "path-col", "", "column holding each row's image file, absolute path or glob (relative to --images); rows without one match by code")
+	fs.IntVar(&c.HeaderRow, "header-row", 0, "header row number; rows up to it are skipped (default 1 with header names)")
+	fs.BoolVar(&c.MatchIgnoreCase, "match-ignore-case", false, "match codes and filenames case-insensitively")
+	fs.BoolVar(&c.MatchFoldDiacritics, "match-fold-diacritics", false, "ignore accents when matching (e.g. Ảnh matches Anh)")
+	fs.BoolVar(&c.MatchStripSeparators, "match-strip-separators", false, "ignore spaces and punctuation when matching")
+	fs.IntVar(&c.MatchPadZeros, "match-pad-zeros", 0, "left-pad numeric codes with zeros to this width when matching")
+	fs.StringVar(&c.MatchPattern, "match-pattern", "", "filename pattern around the code, e.g. IMG_{code}_front")
+	fs.BoolVar(&c.ScanRecursive, "recursive", false, "scan subfolders of the image directory")
+	fs.IntVar(&c.ScanMaxDepth, "max-depth", 0, "maximum subfolder depth when recursive (0 = unlimited)")
+	fs.StringVar(&c.ScanInclude, "include", "", "comma separated glob patterns of images to include")
+	fs.StringVar(&c.ScanExclude, "exclude", "", "comma separated glob patterns of images and folders to skip")
+	fs.StringVar(&c.ScanSymlinks, "symlinks", engine.SymlinkFiles, "symlink policy: files, skip or follow")
+	fs.StringVar(&c.ScanFolderPriority, "folder-priority", "", "comma separated subfolders that win when a filename exists in several folders")
+	fs.StringVar(&c.VariantSuffix, "variant-suffix", "", "suffix pattern grouping several images per code, e.g. _{n}")
+	fs.IntVar(&c.VariantMax, "variant-max", 0, "maximum images per code (0 = unlimited)")
+	fs.StringVar(&c.VariantLayout, "variant-layout", engine.LayoutAcross, "where variants go: across (next columns) or stack (same cell)")
+	fs.StringVar(&c.VariantOrder, "variant-order", engine.OrderSuffix, "variant ordering: suffix or name")
+	fs.StringVar(&c.ConflictPolicy, "conflict-policy", engine.ConflictExtension, "choice between P001.png and P001.jpg: extension, resolution, newest or fail")
+	fs.StringVar(&c.ExtensionOrder, "extension-order", "", "comma separated extension preference for the extension policy (default .png,.jpg,.jpeg,.webp,.gif,.bmp,.tif,.tiff)")
+	fs.BoolVar(&c.KeepOriginal, "keep-original", false, "embed the original image files instead of downscaled copies")
+	fs.Float64Var(&c.DPIScale, "dpi-scale", 1, "pixels per displayed pixel of the downscaled images, e.g. 2 for print")
+	fs.IntVar(&c.ImageQuality, "quality", engine.DefaultJPEGQuality, "JPEG quality of the downscaled images (1-100)")
+	fs.StringVar(&c.ImageFormat, "format", engine.FormatAuto, "format of the downscaled images: auto, jpeg or png")
+	fs.BoolVar(&c.Trim, "trim", false, "crop near-uniform borders of the images")
+	c.TrimTolerance = fs.Int("trim-tolerance", engine.DefaultTrimTolerance, "channel difference still counted as border (0-255)")
+	fs.BoolVar(&c.Pad, "pad", false, "pad the images to the cell aspect ratio")
+	fs.StringVar(&c.Background, "background", "#FFFFFF", "padding colour as #RRGGBB")
+	fs.StringVar(&c.CacheDir, "cache-dir", "", "directory caching processed images between runs (default: no cache)")
+	fs.IntVar(&c.CacheMaxMB, "cache-max-mb", engine.DefaultCacheMaxBytes>>20, "cache size limit in MB; least recently used images are evicted")
+	fs.StringVar(&c.Compatibility, "compat", engine.CompatModern, "application that opens the workbook: modern (Excel 2016 and later) or libreoffice; other formats are transcoded")
+	fs.StringVar(&c.Align, "align", engine.AlignCenter, "picture alignment inside the cell: center, top-left or bottom-center")
+	c.MarginX = fs.Int("margin-x", engine.DefaultMargin, "left and right gap between picture and cell border in pixels")
+	c.MarginY = fs.Int("margin-y", engine.DefaultMargin, "top and bottom gap between picture and cell border in pixels")
+	fs.StringVar(&c.Positioning, "positioning", engine.PositionOneCell, "picture anchoring: oneCell, twoCell (move and size with cells, kept with rows when sorting) or absolute")
+	fs.StringVar(&c.PictureName, "picture-name", "", "picture name template, e.g. {code}; placeholders {code} {file} {sheet} {row} {col:X}")
+	fs.StringVar(&c.PictureAltText, "alt-text", "", "picture alt text template, e.g. \"{col:C} ({code})\"")
+	fs.StringVar(&c.PictureLink, "picture-link", "", "hyperlink of the pictures: file (the original image) or a URL template")
+	fs.BoolVar(&c.LockAspectRatio, "lock-aspect-ratio", false, "keep the aspect ratio when pictures are resized in Excel")
+	fs.BoolVar(&c.UnlockPictures, "unlock-pictures", false, "allow changing pictures on protected sheets")
+	fs.BoolVar(&c.NoPrintPictures, "no-print-pictures", false, "leave pictures out of printouts")
+	fs.StringVar(&c.RowHeightMode, "row-height-mode", engine.RowHeightFixed, "fixed (every matched row gets --row-height) or auto (rows fit the picture aspect ratio)")
+	c.MinRowHeight = fs.Float64("min-row-height", engine.DefaultMinRowHeight, "lowest automatic row height in points")
+	c.MaxRowHeight = fs.Float64("max-row-height", engine.DefaultMaxRowHeight, "highest automatic row height in points")
+	fs.StringVar(&c.UnmatchedRows, "unmatched-rows", engine.UnmatchedKeep, "rows without an image: keep their height or resize them to --row-height")
+	fs.StringVar(&c.StatusCol, "status-col", "", "column to fill with OK, MISSING or ERROR per row")
+	fs.StringVar(&c.FileCol, "file-col", "", "column to fill with the matched image file")
+	fs.StringVar(&c.DimsCol, "dims-col", "", "column to fill with the original pixel size, e.g. 800x600")
+	fs.StringVar(&c.SizeCol, "size-col", "", "column to fill with the image file size in bytes")
+	fs.StringVar(&c.LinkCol, "link-col", "", "column to fill with a hyperlink to the image file")
+	fs.BoolVar(&c.HighlightMissing, "highlight-missing", false, "highlight rows without an image (needs --status-col)")
+	fs.StringVar(&c.ExistingPictures, "existing", engine.ExistingAdd, "pictures already in the image cells: add, skip those cells, replace them, or clear the image columns first")
+	fs.BoolVar(&c.KeepColors, "keep-colors", false, "do not convert CMYK and wide-gamut (ICC profile) images to sRGB")
+	fs.BoolVar(&c.ValidateFullDecode, "full-decode", false, "decode every image completely to catch truncated files, even with --keep-original")
+	fs.IntVar(&c.MaxFileMB, "max-file-mb", 0, "reject image files larger than this many MB (0 = no limit)")
+	fs.IntVar(&c.MaxPixels, "max-pixels", engine.DefaultMaxPixels, "reject images with more pixels than this")
+	fs.StringVar(&c.QuarantineDir, "quarantine-dir", "", "directory listing rejected image files with the reason")
+	fs.BoolVar(&c.QuarantineMove, "quarantine-move", false, "also move rejected image files into --quarantine-dir")
+	fs.BoolVar(&opts.FailOnMissing, "fail-on-missing", false, fmt.Sprintf("exit with code %d when some codes have no image", ExitMissing))
+	fs.BoolVar(&opts.Quiet, "quiet", false, "do not print progress to stderr")
+
+	if err := fs.Parse(args); err != nil {
+		return opts, err
+	}
+	if fs.NArg() > 0 {
+		return opts, fmt.Errorf("unexpected arguments: %v", fs.Args())
+	}
+	c.Sheets = parseSheetTargets(*sheets)
+	if c.ExcelPath == "" {
+		return opts, errors.New("--excel is required")
+	}
+	if c.ImageDir == "" {
+		return opts, errors.New("--images is required")
+	}
+	return opts, nil
+}
+
+// parseSheetTargets parses "Name[:codeCol[:imageCol]]" items separated by commas
+func parseSheetTargets(s string) []engine.SheetTarget {
+	var targets []engine.SheetTarget
+	for _, item := range splitList(s) {
+		parts := strings.Split(item, ":")
+		t := engine.SheetTarget{Name: strings.TrimSpace(parts[0])}
+		if len(parts) > 1 {
+			t.CodeCol = strings.TrimSpace(parts[1])
+		}
+		if len(parts) > 2 {
+			t.ImageCol = strings.TrimSpace(parts[2])
+		}
+		targets = append(targets, t)
+	}
+	return targets
+}
+
+// runCLI executes the headless import and returns the process exit code.
+// Progress goes to stderr; the output path and missing-code summary go to stdout.
+func runCLI(ctx context.Context, args []string, stdout, stderr io.Writer) int {
+	opts, err := parseCLIArgs(args, stderr)
+	if err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return ExitOK
+		}
+		fmt.Fprintf(stderr, "Error: %v\n", err)
+		return ExitUsage
+	}
+
+	p := opts.Config.newProcessor()
+
+	progressChan := make(chan float64, 100)
+	progressDone := make(chan struct{})
+	p.SetProgressChan(progressChan)
+
+	go func() {
+		defer close(progressDone)
+		last := -1
+		for progress := range progressChan {
+			percent := int(progress * 100)
+			if opts.Quiet || percent == last {
+				continue
+			}
+			last = percent
+			fmt.Fprintf(stderr, "Progress: %d%%\n", percent)
+		}
+	}()
+
+	err = p.Run(ctx)
+	close(progressChan)
+	<-progressDone
+	if err != nil {
+		fmt.Fprintf(stderr, "Error: processing failed: %v\n", err)
+		return ExitFailure
+	}
+
+	fmt.Fprintf(stdout, "output: %s\n", p.OutputPath)
+	if p.ReportPath != "" {
+		fmt.Fprintf(stdout, "report: %s\n", p.ReportPath)
+	}
+	if p.ReportCSVPath != "" {
+		fmt.Fprintf(stdout, "report-csv: %s\n", p.ReportCSVPath)
+	}
+	fmt.Fprintf(stdout, "processed: %d\n", p.ProcessedCount)
+	fmt.Fprintf(stdout, "missing: %d\n", len(p.MissingCodes))
+	fmt.Fprintf(stdout, "replaced: %d\n", p.ReplacedCount)
+	fmt.Fprintf(stdout, "bytes: %d -> %d (saved %d)\n", p.Report.Totals.OriginalBytes, p.Report.Totals.EmbeddedBytes, p.Report.Totals.SavedBytes)
+	fmt.Fprintf(stdout, "deduplicated: %d (%d bytes)\n", p.Report.Totals.Deduplicated, p.Report.Totals.DeduplicatedBytes)
+	if c := p.Report.Cache; c != nil {
+		fmt.Fprintf(stdout, "cache: hits=%d misses=%d evicted=%d\n", c.Hits, c.Misses, c.Evicted)
+	}
+	for _, code := range p.MissingCodes {
+		fmt.Fprintf(stdout, "missing-code: %s\n", code)
+	}
+	for _, m := range p.Matches {
+		if m.Rule != engine.RuleExact {
+			fmt.Fprintf(stdout, "matched: %s -> %s (%s)\n", m.Code, m.File, m.Rule)
+		}
+	}
+	for _, s := range p.SheetStats {
+		fmt.Fprintf(stdout, "sheet: %s processed=%d missing=%d\n", s.Name, s.Processed, s.Missing)
+	}
+	for _, d := range p.Duplicates {
+		fmt.Fprintf(stdout, "duplicate: %s!%s rows %s\n", d.Sheet, d.Code, strings.Trim(fmt.Sprint(d.Rows), "[]"))
+	}
+	for _, c := range p.Conflicts {
+		fmt.Fprintf(stdout, "conflict: %s -> %s (candidates: %s)\n", c.Name, c.Chosen, strings.Join(c.Candidates, ", "))
+	}
+	for _, c := range p.Collisions {
+		fmt.Fprintf(stdout, "collision: %s -> %s (ignored: %s)\n", c.Name, c.Chosen, strings.Join(c.Ignored, ", "))
+	}
+	for _, row := range p.Report.Rows {
+		if row.Status == engine.StatusDecodeError || row.Status == engine.StatusInsertError {
+			fmt.Fprintf(stdout, "error: %s!%d %s %s: %s\n", row.Sheet, row.Row, row.File, row.Status, row.Error)
+		}
+	}
+	for _, q := range p.Quarantined {
+		fmt.Fprintf(stdout, "quarantined: %s (%s)\n", q.File, q.Reason)
+	}
+
+	if opts.FailOnMissing && len(p.MissingCodes) > 0 {
+		return ExitMissing
+	}
+	return ExitOK
+}
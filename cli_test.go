@@ -0,0 +1,111 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"imagetoexcel/internal/engine"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestParseCLIArgs(t *testing.T) {
+	tests := []struct {
+		name    string
+		args    []string
+		wantErr bool
+	}{
+		{"missing excel", []string{"--images", "dir"}, true},
+		{"missing images", []string{"--excel", "a.xlsx"}, true},
+		{"extra args", []string{"--excel", "a.xlsx", "--images", "dir", "extra"}, true},
+		{"unknown flag", []string{"--excel", "a.xlsx", "--images", "dir", "--nope"}, true},
+		{"valid", []string{"--excel", "a.xlsx", "--images", "dir", "--code-col", "B", "--workers", "3"}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := parseCLIArgs(tt.args, &bytes.Buffer{})
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseCLIArgs(%v) error = %v, wantErr %v", tt.args, err, tt.wantErr)
+			}
+		})
+	}
+
+	opts, _ := parseCLIArgs([]string{"--excel", "a.xlsx", "--images", "dir", "--sheet", "Data", "--row-height", "50"}, &bytes.Buffer{})
+	if opts.Config.SheetName != "Data" || opts.Config.RowHeight != 50 || opts.Config.CodeCol != "A" {
+		t.Errorf("unexpected config: %+v", opts.Config)
+	}
+
+	// Zero is a real value, not a request for the default
+	opts, _ = parseCLIArgs([]string{"--excel", "a.xlsx", "--images", "dir", "--margin-x", "0"}, &bytes.Buffer{})
+	if x, y := opts.Config.MarginX, opts.Config.MarginY; x == nil || *x != 0 || y == nil || *y != engine.DefaultMargin {
+		t.Errorf("margins = %v, %v; want 0 and %d", x, y, engine.DefaultMargin)
+	}
+}
+
+func TestParseSheetTargets(t *testing.T) {
+	got := parseSheetTargets("Brand A, Brand B:C:G ,Brand C:B")
+	want := []engine.SheetTarget{
+		{Name: "Brand A"},
+		{Name: "Brand B", CodeCol: "C", ImageCol: "G"},
+		{Name: "Brand C", CodeCol: "B"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("parseSheetTargets() = %+v, want %+v", got, want)
+	}
+}
+
+func TestRunCLI(t *testing.T) {
+	tempDir := t.TempDir()
+	excelPath := filepath.Join(tempDir, "test.xlsx")
+	imageDir := filepath.Join(tempDir, "images")
+	_ = os.Mkdir(imageDir, 0755)
+
+	f := excelize.NewFile()
+	_ = f.SetCellValue("Sheet1", "A1", "P001")
+	_ = f.SetCellValue("Sheet1", "A2", "P002")
+	if err := f.SaveAs(excelPath); err != nil {
+		t.Fatal(err)
+	}
+
+	img, err := os.Create(filepath.Join(imageDir, "P001.png"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	_ = png.Encode(img, image.NewRGBA(image.Rect(0, 0, 10, 10)))
+	img.Close()
+
+	args := []string{"--excel", excelPath, "--images", imageDir, "--image-col", "B", "--workers", "2"}
+
+	var stdout, stderr bytes.Buffer
+	if code := runCLI(context.Background(), args, &stdout, &stderr); code != ExitOK {
+		t.Fatalf("runCLI() = %d, want %d; stderr: %s", code, ExitOK, stderr.String())
+	}
+	out := stdout.String()
+	if !strings.Contains(out, "processed: 1") || !strings.Contains(out, "missing-code: P002") {
+		t.Errorf("unexpected stdout: %q", out)
+	}
+	if !strings.Contains(out, "_output_") {
+		t.Errorf("stdout does not contain the output path: %q", out)
+	}
+
+	stdout.Reset()
+	args = append(args, "--fail-on-missing", "--quiet")
+	if code := runCLI(context.Background(), args, &stdout, &stderr); code != ExitMissing {
+		t.Errorf("runCLI() with --fail-on-missing = %d, want %d", code, ExitMissing)
+	}
+
+	if code := runCLI(context.Background(), []string{"--excel", excelPath}, &stdout, &stderr); code != ExitUsage {
+		t.Errorf("runCLI() without --images = %d, want %d", code, ExitUsage)
+	}
+	if code := runCLI(context.Background(), []string{"--excel", filepath.Join(tempDir, "nope.xlsx"), "--images", imageDir}, &stdout, &stderr); code != ExitFailure {
+		t.Errorf("runCLI() with missing workbook = %d, want %d", code, ExitFailure)
+	}
+}
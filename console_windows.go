@@ -0,0 +1,44 @@
+//go:build windows
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// attachParentProcess is ATTACH_PARENT_PROCESS, the console of the parent process
+const attachParentProcess = ^uintptr(0)
+
+var procAttachConsole = syscall.NewLazyDLL("kernel32.dll").NewProc("AttachConsole")
+
+// attachConsole connects the standard streams of the headless mode to the
+// console it was started from. Release builds use the GUI subsystem and start
+// without a console; streams redirected to files or pipes are kept.
+func attachConsole() {
+	stdout, _ := syscall.GetStdHandle(syscall.STD_OUTPUT_HANDLE)
+	stderr, _ := syscall.GetStdHandle(syscall.STD_ERROR_HANDLE)
+	if validHandle(stdout) && validHandle(stderr) {
+		return
+	}
+	if ok, _, _ := procAttachConsole.Call(attachParentProcess); ok == 0 {
+		return // No parent console, e.g. started by Task Scheduler
+	}
+	// Read access lets Go detect the console and write UTF-16, so non-ASCII
+	// codes and paths are not garbled
+	con, err := os.OpenFile("CONOUT$", os.O_RDWR, 0)
+	if err != nil {
+		return
+	}
+	if !validHandle(stdout) {
+		os.Stdout = con
+	}
+	if !validHandle(stderr) {
+		os.Stderr = con
+	}
+}
+
+// validHandle reports whether a standard handle refers to an open stream
+func validHandle(h syscall.Handle) bool {
+	return h != 0 && h != syscall.InvalidHandle
+}
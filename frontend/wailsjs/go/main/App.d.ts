@@ -14,6 +14,8 @@ export function OpenFileLocation(arg1:string):Promise<void>;
 
 export function PerformUpdate(arg1:string):Promise<boolean>;
 
+export function Preview(arg1:main.Config):Promise<main.PreviewResult>;
+
 export function Process(arg1:main.Config):Promise<main.ProcessResult>;
 
 export function SelectExcelFile():Promise<string>;
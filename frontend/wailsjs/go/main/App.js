@@ -26,6 +26,10 @@ export function PerformUpdate(arg1) {
   return window['go']['main']['App']['PerformUpdate'](arg1);
 }
 
+export function Preview(arg1) {
+  return window['go']['main']['App']['Preview'](arg1);
+}
+
 export function Process(arg1) {
   return window['go']['main']['App']['Process'](arg1);
 }
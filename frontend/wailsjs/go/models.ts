@@ -1,3 +1,351 @@
+export namespace engine {
+	
+	export class CacheStats {
+	    hits: number;
+	    misses: number;
+	    evicted: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new CacheStats(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.hits = source["hits"];
+	        this.misses = source["misses"];
+	        this.evicted = source["evicted"];
+	    }
+	}
+	export class Collision {
+	    name: string;
+	    chosen: string;
+	    ignored: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Collision(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.chosen = source["chosen"];
+	        this.ignored = source["ignored"];
+	    }
+	}
+	export class Conflict {
+	    name: string;
+	    chosen: string;
+	    candidates: string[];
+	    policy: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new Conflict(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.chosen = source["chosen"];
+	        this.candidates = source["candidates"];
+	        this.policy = source["policy"];
+	    }
+	}
+	export class Duplicate {
+	    sheet: string;
+	    code: string;
+	    rows: number[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Duplicate(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sheet = source["sheet"];
+	        this.code = source["code"];
+	        this.rows = source["rows"];
+	    }
+	}
+	export class RowRef {
+	    sheet: string;
+	    row: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new RowRef(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sheet = source["sheet"];
+	        this.row = source["row"];
+	    }
+	}
+	export class Match {
+	    code: string;
+	    file: string;
+	    variants?: string[];
+	    rule: string;
+	    rows?: RowRef[];
+	
+	    static createFrom(source: any = {}) {
+	        return new Match(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.code = source["code"];
+	        this.file = source["file"];
+	        this.variants = source["variants"];
+	        this.rule = source["rule"];
+	        this.rows = this.convertValues(source["rows"], RowRef);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PreviewRow {
+	    sheet: string;
+	    row: number;
+	    code: string;
+	    file?: string;
+	    variants?: string[];
+	    rule?: string;
+	    width: number;
+	    height: number;
+	    problems?: string[];
+	
+	    static createFrom(source: any = {}) {
+	        return new PreviewRow(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sheet = source["sheet"];
+	        this.row = source["row"];
+	        this.code = source["code"];
+	        this.file = source["file"];
+	        this.variants = source["variants"];
+	        this.rule = source["rule"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.problems = source["problems"];
+	    }
+	}
+	export class Quarantined {
+	    file: string;
+	    reason: string;
+	    error: string;
+	    moved: boolean;
+	
+	    static createFrom(source: any = {}) {
+	        return new Quarantined(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.file = source["file"];
+	        this.reason = source["reason"];
+	        this.error = source["error"];
+	        this.moved = source["moved"];
+	    }
+	}
+	export class ReportRow {
+	    sheet: string;
+	    row: number;
+	    code: string;
+	    file?: string;
+	    rule?: string;
+	    slot: number;
+	    status: string;
+	    error?: string;
+	    width: number;
+	    height: number;
+	    scaledWidth: number;
+	    scaledHeight: number;
+	    bytes: number;
+	    embeddedBytes: number;
+	    loadMs: number;
+	    insertMs: number;
+	    deduplicated: boolean;
+	    replaced: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReportRow(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.sheet = source["sheet"];
+	        this.row = source["row"];
+	        this.code = source["code"];
+	        this.file = source["file"];
+	        this.rule = source["rule"];
+	        this.slot = source["slot"];
+	        this.status = source["status"];
+	        this.error = source["error"];
+	        this.width = source["width"];
+	        this.height = source["height"];
+	        this.scaledWidth = source["scaledWidth"];
+	        this.scaledHeight = source["scaledHeight"];
+	        this.bytes = source["bytes"];
+	        this.embeddedBytes = source["embeddedBytes"];
+	        this.loadMs = source["loadMs"];
+	        this.insertMs = source["insertMs"];
+	        this.deduplicated = source["deduplicated"];
+	        this.replaced = source["replaced"];
+	    }
+	}
+	export class ReportTotals {
+	    rows: number;
+	    inserted: number;
+	    missing: number;
+	    decodeErrors: number;
+	    insertErrors: number;
+	    skipped: number;
+	    orphans: number;
+	    replaced: number;
+	    originalBytes: number;
+	    embeddedBytes: number;
+	    savedBytes: number;
+	    deduplicated: number;
+	    deduplicatedBytes: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new ReportTotals(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.rows = source["rows"];
+	        this.inserted = source["inserted"];
+	        this.missing = source["missing"];
+	        this.decodeErrors = source["decodeErrors"];
+	        this.insertErrors = source["insertErrors"];
+	        this.skipped = source["skipped"];
+	        this.orphans = source["orphans"];
+	        this.replaced = source["replaced"];
+	        this.originalBytes = source["originalBytes"];
+	        this.embeddedBytes = source["embeddedBytes"];
+	        this.savedBytes = source["savedBytes"];
+	        this.deduplicated = source["deduplicated"];
+	        this.deduplicatedBytes = source["deduplicatedBytes"];
+	    }
+	}
+	export class Report {
+	    workbook: string;
+	    output: string;
+	    // Go type: time
+	    startedAt: any;
+	    // Go type: time
+	    finishedAt: any;
+	    elapsedMs: number;
+	    totals: ReportTotals;
+	    rows: ReportRow[];
+	    orphans: string[];
+	    cache?: CacheStats;
+	    quarantined: Quarantined[];
+	    cleared: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new Report(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.workbook = source["workbook"];
+	        this.output = source["output"];
+	        this.startedAt = this.convertValues(source["startedAt"], null);
+	        this.finishedAt = this.convertValues(source["finishedAt"], null);
+	        this.elapsedMs = source["elapsedMs"];
+	        this.totals = this.convertValues(source["totals"], ReportTotals);
+	        this.rows = this.convertValues(source["rows"], ReportRow);
+	        this.orphans = source["orphans"];
+	        this.cache = this.convertValues(source["cache"], CacheStats);
+	        this.quarantined = this.convertValues(source["quarantined"], Quarantined);
+	        this.cleared = source["cleared"];
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	
+	
+	
+	export class SheetStat {
+	    name: string;
+	    codeCol: string;
+	    imageCol: string;
+	    rows: number;
+	    processed: number;
+	    missing: number;
+	
+	    static createFrom(source: any = {}) {
+	        return new SheetStat(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.codeCol = source["codeCol"];
+	        this.imageCol = source["imageCol"];
+	        this.rows = source["rows"];
+	        this.processed = source["processed"];
+	        this.missing = source["missing"];
+	    }
+	}
+	export class SheetTarget {
+	    name: string;
+	    codeCol: string;
+	    imageCol: string;
+	    codeHeader: string;
+	    imageHeader: string;
+	
+	    static createFrom(source: any = {}) {
+	        return new SheetTarget(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.name = source["name"];
+	        this.codeCol = source["codeCol"];
+	        this.imageCol = source["imageCol"];
+	        this.codeHeader = source["codeHeader"];
+	        this.imageHeader = source["imageHeader"];
+	    }
+	}
+
+}
+
 export namespace main {
 	
 	export class Config {
@@ -9,6 +357,67 @@ export namespace main {
 	    rowHeight: number;
 	    colWidth: number;
 	    workerCount: number;
+	    sheets: engine.SheetTarget[];
+	    allSheets: boolean;
+	    codeHeader: string;
+	    imageHeader: string;
+	    headerRow: number;
+	    pathCol: string;
+	    matchIgnoreCase: boolean;
+	    matchFoldDiacritics: boolean;
+	    matchStripSeparators: boolean;
+	    matchPadZeros: number;
+	    matchPattern: string;
+	    scanRecursive: boolean;
+	    scanMaxDepth: number;
+	    scanInclude: string;
+	    scanExclude: string;
+	    scanSymlinks: string;
+	    scanFolderPriority: string;
+	    variantSuffix: string;
+	    variantMax: number;
+	    variantLayout: string;
+	    variantOrder: string;
+	    conflictPolicy: string;
+	    extensionOrder: string;
+	    keepOriginal: boolean;
+	    dpiScale: number;
+	    imageQuality: number;
+	    imageFormat: string;
+	    trim: boolean;
+	    trimTolerance?: number;
+	    pad: boolean;
+	    background: string;
+	    cacheDir: string;
+	    cacheMaxMb: number;
+	    compatibility: string;
+	    keepColors: boolean;
+	    align: string;
+	    marginX?: number;
+	    marginY?: number;
+	    positioning: string;
+	    pictureName: string;
+	    pictureAltText: string;
+	    pictureLink: string;
+	    lockAspectRatio: boolean;
+	    unlockPictures: boolean;
+	    noPrintPictures: boolean;
+	    rowHeightMode: string;
+	    minRowHeight?: number;
+	    maxRowHeight?: number;
+	    unmatchedRows: string;
+	    existingPictures: string;
+	    statusCol: string;
+	    fileCol: string;
+	    dimsCol: string;
+	    sizeCol: string;
+	    linkCol: string;
+	    highlightMissing: boolean;
+	    validateFullDecode: boolean;
+	    maxFileMb: number;
+	    maxPixels: number;
+	    quarantineDir: string;
+	    quarantineMove: boolean;
 	
 	    static createFrom(source: any = {}) {
 	        return new Config(source);
@@ -24,13 +433,147 @@ export namespace main {
 	        this.rowHeight = source["rowHeight"];
 	        this.colWidth = source["colWidth"];
 	        this.workerCount = source["workerCount"];
+	        this.sheets = this.convertValues(source["sheets"], engine.SheetTarget);
+	        this.allSheets = source["allSheets"];
+	        this.codeHeader = source["codeHeader"];
+	        this.imageHeader = source["imageHeader"];
+	        this.headerRow = source["headerRow"];
+	        this.pathCol = source["pathCol"];
+	        this.matchIgnoreCase = source["matchIgnoreCase"];
+	        this.matchFoldDiacritics = source["matchFoldDiacritics"];
+	        this.matchStripSeparators = source["matchStripSeparators"];
+	        this.matchPadZeros = source["matchPadZeros"];
+	        this.matchPattern = source["matchPattern"];
+	        this.scanRecursive = source["scanRecursive"];
+	        this.scanMaxDepth = source["scanMaxDepth"];
+	        this.scanInclude = source["scanInclude"];
+	        this.scanExclude = source["scanExclude"];
+	        this.scanSymlinks = source["scanSymlinks"];
+	        this.scanFolderPriority = source["scanFolderPriority"];
+	        this.variantSuffix = source["variantSuffix"];
+	        this.variantMax = source["variantMax"];
+	        this.variantLayout = source["variantLayout"];
+	        this.variantOrder = source["variantOrder"];
+	        this.conflictPolicy = source["conflictPolicy"];
+	        this.extensionOrder = source["extensionOrder"];
+	        this.keepOriginal = source["keepOriginal"];
+	        this.dpiScale = source["dpiScale"];
+	        this.imageQuality = source["imageQuality"];
+	        this.imageFormat = source["imageFormat"];
+	        this.trim = source["trim"];
+	        this.trimTolerance = source["trimTolerance"];
+	        this.pad = source["pad"];
+	        this.background = source["background"];
+	        this.cacheDir = source["cacheDir"];
+	        this.cacheMaxMb = source["cacheMaxMb"];
+	        this.compatibility = source["compatibility"];
+	        this.keepColors = source["keepColors"];
+	        this.align = source["align"];
+	        this.marginX = source["marginX"];
+	        this.marginY = source["marginY"];
+	        this.positioning = source["positioning"];
+	        this.pictureName = source["pictureName"];
+	        this.pictureAltText = source["pictureAltText"];
+	        this.pictureLink = source["pictureLink"];
+	        this.lockAspectRatio = source["lockAspectRatio"];
+	        this.unlockPictures = source["unlockPictures"];
+	        this.noPrintPictures = source["noPrintPictures"];
+	        this.rowHeightMode = source["rowHeightMode"];
+	        this.minRowHeight = source["minRowHeight"];
+	        this.maxRowHeight = source["maxRowHeight"];
+	        this.unmatchedRows = source["unmatchedRows"];
+	        this.existingPictures = source["existingPictures"];
+	        this.statusCol = source["statusCol"];
+	        this.fileCol = source["fileCol"];
+	        this.dimsCol = source["dimsCol"];
+	        this.sizeCol = source["sizeCol"];
+	        this.linkCol = source["linkCol"];
+	        this.highlightMissing = source["highlightMissing"];
+	        this.validateFullDecode = source["validateFullDecode"];
+	        this.maxFileMb = source["maxFileMb"];
+	        this.maxPixels = source["maxPixels"];
+	        this.quarantineDir = source["quarantineDir"];
+	        this.quarantineMove = source["quarantineMove"];
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
+	}
+	export class PreviewResult {
+	    success: boolean;
+	    message: string;
+	    rows: engine.PreviewRow[];
+	    orphans: string[];
+	    missingCodes: string[];
+	    collisions: engine.Collision[];
+	    duplicates: engine.Duplicate[];
+	    conflicts: engine.Conflict[];
+	    sheetStats: engine.SheetStat[];
+	
+	    static createFrom(source: any = {}) {
+	        return new PreviewResult(source);
+	    }
+	
+	    constructor(source: any = {}) {
+	        if ('string' === typeof source) source = JSON.parse(source);
+	        this.success = source["success"];
+	        this.message = source["message"];
+	        this.rows = this.convertValues(source["rows"], engine.PreviewRow);
+	        this.orphans = source["orphans"];
+	        this.missingCodes = source["missingCodes"];
+	        this.collisions = this.convertValues(source["collisions"], engine.Collision);
+	        this.duplicates = this.convertValues(source["duplicates"], engine.Duplicate);
+	        this.conflicts = this.convertValues(source["conflicts"], engine.Conflict);
+	        this.sheetStats = this.convertValues(source["sheetStats"], engine.SheetStat);
+	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class ProcessResult {
 	    success: boolean;
 	    message: string;
 	    missingCodes: string[];
+	    matches: engine.Match[];
+	    collisions: engine.Collision[];
+	    duplicates: engine.Duplicate[];
+	    conflicts: engine.Conflict[];
+	    sheetStats: engine.SheetStat[];
 	    outputPath: string;
+	    report?: engine.Report;
+	    reportPath: string;
+	    reportCsvPath: string;
+	    quarantined: engine.Quarantined[];
 	
 	    static createFrom(source: any = {}) {
 	        return new ProcessResult(source);
@@ -41,8 +584,35 @@ export namespace main {
 	        this.success = source["success"];
 	        this.message = source["message"];
 	        this.missingCodes = source["missingCodes"];
+	        this.matches = this.convertValues(source["matches"], engine.Match);
+	        this.collisions = this.convertValues(source["collisions"], engine.Collision);
+	        this.duplicates = this.convertValues(source["duplicates"], engine.Duplicate);
+	        this.conflicts = this.convertValues(source["conflicts"], engine.Conflict);
+	        this.sheetStats = this.convertValues(source["sheetStats"], engine.SheetStat);
 	        this.outputPath = source["outputPath"];
+	        this.report = this.convertValues(source["report"], engine.Report);
+	        this.reportPath = source["reportPath"];
+	        this.reportCsvPath = source["reportCsvPath"];
+	        this.quarantined = this.convertValues(source["quarantined"], engine.Quarantined);
 	    }
+	
+		convertValues(a: any, classs: any, asMap: boolean = false): any {
+		    if (!a) {
+		        return a;
+		    }
+		    if (a.slice && a.map) {
+		        return (a as any[]).map(elem => this.convertValues(elem, classs));
+		    } else if ("object" === typeof a) {
+		        if (asMap) {
+		            for (const key of Object.keys(a)) {
+		                a[key] = new classs(a[key]);
+		            }
+		            return a;
+		        }
+		        return new classs(a);
+		    }
+		    return a;
+		}
 	}
 	export class UpdateInfo {
 	    available: boolean;
@@ -0,0 +1,165 @@
+package engine
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"image"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultCacheMaxBytes is used when CacheOptions.MaxBytes is 0
+const DefaultCacheMaxBytes = 1 << 30
+
+// cacheVersion is part of every key; bump it when resampling output changes
+const cacheVersion = "v2"
+
+// cacheExt is the extension of cache entries
+const cacheExt = ".img"
+
+// CacheOptions controls the on-disk cache of processed images
+type CacheOptions struct {
+	Dir      string // Cache directory, empty disables the cache
+	MaxBytes int64  // Size limit; least recently used entries are evicted (default DefaultCacheMaxBytes)
+}
+
+// CacheStats reports how the cache was used during a run
+type CacheStats struct {
+	Hits    int `json:"hits"`
+	Misses  int `json:"misses"`
+	Evicted int `json:"evicted"`
+}
+
+// imageCache stores resampled images keyed by source content and settings.
+// Each entry holds the output extension on its first line, then the image
+// bytes. The modification time of an entry records its last use.
+type imageCache struct {
+	dir          string
+	maxBytes     int64
+	hits, misses atomic.Int64
+}
+
+// openCache creates the cache directory when needed
+func openCache(opts CacheOptions) (*imageCache, error) {
+	if err := os.MkdirAll(opts.Dir, 0755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+	maxBytes := opts.MaxBytes
+	if maxBytes <= 0 {
+		maxBytes = DefaultCacheMaxBytes
+	}
+	return &imageCache{dir: opts.Dir, maxBytes: maxBytes}, nil
+}
+
+// cacheKey identifies the output of resampling data with the given settings.
+// Settings are hashed with their defaults applied, and the trim tolerance and
+// background only when they are used, so equivalent settings share entries.
+func cacheKey(data []byte, ext string, boxW, boxH float64, keepable bool, o ResampleOptions) string {
+	tolerance, background := 0, ""
+	if o.Trim {
+		tolerance = o.trimTolerance()
+	}
+	if o.Pad {
+		bg, _ := o.background()
+		background = fmt.Sprintf("%02x%02x%02x", bg.R, bg.G, bg.B)
+	}
+	h := sha256.New()
+	h.Write(data)
+	fmt.Fprintf(h, "|%s|%s|%.3f|%.3f|%t|%g|%d|%s|%t|%d|%t|%s|%t", cacheVersion, strings.ToLower(ext), boxW, boxH, keepable,
+		o.dpiScale(), o.quality(), o.format(), o.Trim, tolerance, o.Pad, background, o.KeepColors)
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// path returns the entry file of key
+func (c *imageCache) path(key string) string {
+	return filepath.Join(c.dir, key+cacheExt)
+}
+
+// get returns the cached image for key and marks it as recently used
+func (c *imageCache) get(key string) (resampled, bool) {
+	path := c.path(key)
+	entry, err := os.ReadFile(path)
+	if err != nil {
+		c.misses.Add(1)
+		return resampled{}, false
+	}
+	ext, data, ok := bytes.Cut(entry, []byte("\n"))
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if !ok || err != nil {
+		_ = os.Remove(path) // Damaged entry
+		c.misses.Add(1)
+		return resampled{}, false
+	}
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	c.hits.Add(1)
+	return resampled{data: data, ext: string(ext), width: cfg.Width, height: cfg.Height}, true
+}
+
+// put stores img under key. The entry is written to a temporary file first so
+// concurrent readers never see partial data.
+func (c *imageCache) put(key string, img resampled) error {
+	tmp, err := os.CreateTemp(c.dir, key+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	_, err = tmp.Write(append([]byte(img.ext+"\n"), img.data...))
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), c.path(key))
+	}
+	if err != nil {
+		_ = os.Remove(tmp.Name())
+	}
+	return err
+}
+
+// prune removes the least recently used entries until the cache fits its
+// size limit and returns the number of evicted entries
+func (c *imageCache) prune() (int, error) {
+	dirEntries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return 0, err
+	}
+
+	type entry struct {
+		path string
+		size int64
+		used time.Time
+	}
+	var entries []entry
+	var total int64
+	for _, d := range dirEntries {
+		if d.IsDir() || filepath.Ext(d.Name()) != cacheExt {
+			continue
+		}
+		info, err := d.Info()
+		if err != nil {
+			continue
+		}
+		entries = append(entries, entry{filepath.Join(c.dir, d.Name()), info.Size(), info.ModTime()})
+		total += info.Size()
+	}
+
+	sort.Slice(entries, func(i, j int) bool { return entries[i].used.Before(entries[j].used) })
+	evicted := 0
+	for _, e := range entries {
+		if total <= c.maxBytes {
+			break
+		}
+		if err := os.Remove(e.path); err != nil {
+			continue
+		}
+		total -= e.size
+		evicted++
+	}
+	return evicted, nil
+}
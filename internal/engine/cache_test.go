@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProcessor_RunCache(t *testing.T) {
+	tempDir := t.TempDir()
+	excelPath := filepath.Join(tempDir, "test.xlsx")
+	imageDir := filepath.Join(tempDir, "images")
+	cacheDir := filepath.Join(tempDir, "cache")
+	_ = os.Mkdir(imageDir, 0755)
+
+	if err := createDummyExcel(excelPath, "Sheet1", "A", []string{"P001", "P002", "P003"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = createDummyImage(filepath.Join(imageDir, "P001.png"), 400, 300)
+	_ = createDummyImage(filepath.Join(imageDir, "P002.png"), 300, 400)
+
+	run := func(quality int) *CacheStats {
+		t.Helper()
+		p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 2, 100, 20)
+		p.Cache.Dir = cacheDir
+		p.Resample.Format = FormatJPEG
+		p.Resample.Quality = quality
+		if err := p.Run(context.Background()); err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+		if p.ProcessedCount != 2 {
+			t.Errorf("expected 2 inserted pictures, got %d", p.ProcessedCount)
+		}
+		return p.Report.Cache
+	}
+
+	if got := run(80); got == nil || got.Hits != 0 || got.Misses != 2 {
+		t.Errorf("first run cache stats = %+v, want 0 hits and 2 misses", got)
+	}
+	if got := run(80); got == nil || got.Hits != 2 || got.Misses != 0 {
+		t.Errorf("repeat run cache stats = %+v, want 2 hits and 0 misses", got)
+	}
+	// A different quality is a different key
+	if got := run(60); got == nil || got.Hits != 0 || got.Misses != 2 {
+		t.Errorf("changed quality cache stats = %+v, want 0 hits and 2 misses", got)
+	}
+}
+
+func TestCacheKey(t *testing.T) {
+	key := func(o ResampleOptions) string {
+		return cacheKey([]byte("image"), ".PNG", 130, 65, true, o)
+	}
+	base := key(ResampleOptions{Pad: true})
+	for _, o := range []ResampleOptions{
+		{Pad: true, DPIScale: 1, Quality: DefaultJPEGQuality, Format: FormatAuto},
+		{Pad: true, Background: "#FFFFFF"},
+		{Pad: true, Background: "#ffffff"},
+		{Pad: true, TrimTolerance: ptr(0)}, // Trim is off
+	} {
+		if key(o) != base {
+			t.Errorf("key of %+v differs from the defaults", o)
+		}
+	}
+	for _, o := range []ResampleOptions{
+		{Pad: true, DPIScale: 2},
+		{Pad: true, Quality: 60},
+		{Pad: true, Background: "#ff0000"},
+		{Pad: true, Trim: true},
+		{},
+	} {
+		if key(o) == base {
+			t.Errorf("key of %+v matches the defaults", o)
+		}
+	}
+}
+
+func TestImageCache_GetPut(t *testing.T) {
+	c, err := openCache(CacheOptions{Dir: filepath.Join(t.TempDir(), "cache")})
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := ResampleOptions{}.resample(encodeTestImage(t, FormatPNG, 300, 200), ".png", 60, 60, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if _, ok := c.get("key"); ok {
+		t.Error("get() on an empty cache hit")
+	}
+	if err := c.put("key", img); err != nil {
+		t.Fatalf("put() error: %v", err)
+	}
+	got, ok := c.get("key")
+	if !ok || got.ext != img.ext || got.width != 60 || got.height != 40 || len(got.data) != len(img.data) {
+		t.Errorf("get() = %s %dx%d %d bytes, want %s 60x40 %d bytes", got.ext, got.width, got.height, len(got.data), img.ext, len(img.data))
+	}
+
+	// Damaged entries are dropped
+	_ = os.WriteFile(c.path("bad"), []byte(".png\nnot an image"), 0644)
+	if _, ok := c.get("bad"); ok {
+		t.Error("get() returned a damaged entry")
+	}
+	if _, err := os.Stat(c.path("bad")); !os.IsNotExist(err) {
+		t.Error("damaged entry was not removed")
+	}
+	if c.hits.Load() != 1 || c.misses.Load() != 2 {
+		t.Errorf("hits %d, misses %d; want 1 and 2", c.hits.Load(), c.misses.Load())
+	}
+}
+
+func TestImageCache_Prune(t *testing.T) {
+	dir := t.TempDir()
+	c, err := openCache(CacheOptions{Dir: dir, MaxBytes: 250})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// Four 100 byte entries, "a" used least recently
+	now := time.Now()
+	for i, key := range []string{"a", "b", "c", "d"} {
+		path := c.path(key)
+		_ = os.WriteFile(path, make([]byte, 100), 0644)
+		used := now.Add(time.Duration(i) * time.Minute)
+		_ = os.Chtimes(path, used, used)
+	}
+	_ = os.WriteFile(filepath.Join(dir, "other.txt"), make([]byte, 1000), 0644)
+
+	evicted, err := c.prune()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if evicted != 2 {
+		t.Errorf("prune() evicted %d entries, want 2", evicted)
+	}
+	for key, want := range map[string]bool{"a": false, "b": false, "c": true, "d": true} {
+		_, err := os.Stat(c.path(key))
+		if exists := err == nil; exists != want {
+			t.Errorf("entry %s exists = %v, want %v", key, exists, want)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "other.txt")); err != nil {
+		t.Error("prune() removed a file that is not a cache entry")
+	}
+}
@@ -0,0 +1,358 @@
+package engine
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"io"
+	"math"
+	"sort"
+
+	"golang.org/x/image/draw"
+)
+
+// maxICCSize bounds the size of an embedded colour profile
+const maxICCSize = 4 << 20
+
+// d50ToSRGB converts D50 XYZ, the connection space of ICC profiles, to
+// linear sRGB (Bradford adapted)
+var d50ToSRGB = [3][3]float64{
+	{3.1338561, -1.6168667, -0.4906146},
+	{-0.9787684, 1.9161415, 0.0334540},
+	{0.0719453, -0.2289914, 1.4052427},
+}
+
+// srgbEncode maps linear light in 1/4095 steps to 8-bit sRGB
+var srgbEncode = func() (lut [4096]uint8) {
+	for i := range lut {
+		lut[i] = uint8(math.Round(linearToSRGB(float64(i)/4095) * 255))
+	}
+	return lut
+}()
+
+// srgbToLinear decodes an sRGB value in [0, 1]
+func srgbToLinear(v float64) float64 {
+	if v <= 0.04045 {
+		return v / 12.92
+	}
+	return math.Pow((v+0.055)/1.055, 2.4)
+}
+
+// linearToSRGB encodes linear light in [0, 1] as sRGB
+func linearToSRGB(v float64) float64 {
+	if v <= 0.0031308 {
+		return v * 12.92
+	}
+	return 1.055*math.Pow(v, 1/2.4) - 0.055
+}
+
+// iccTransform converts pixels of a matrix/TRC RGB profile, such as Adobe
+// RGB or Display P3, to sRGB
+type iccTransform struct {
+	linear [3][256]float64 // Tone curve per channel, 8-bit value to linear light
+	matrix [3][3]float64   // Linear profile RGB to linear sRGB
+}
+
+// iccProfile returns the ICC profile embedded in JPEG or PNG data, or nil
+func iccProfile(data []byte) []byte {
+	switch {
+	case bytes.HasPrefix(data, []byte{0xFF, 0xD8}):
+		return jpegICC(data)
+	case bytes.HasPrefix(data, []byte("\x89PNG\r\n\x1a\n")):
+		return pngICC(data)
+	}
+	return nil
+}
+
+// jpegICC joins the APP2 ICC_PROFILE chunks of JPEG data in sequence order
+func jpegICC(data []byte) []byte {
+	type chunk struct {
+		seq  byte
+		data []byte
+	}
+	var chunks []chunk
+	for i := 2; i+4 <= len(data); {
+		if data[i] != 0xFF {
+			break
+		}
+		marker := data[i+1]
+		if marker == 0xDA || marker == 0xD9 {
+			break
+		}
+		size := int(binary.BigEndian.Uint16(data[i+2:]))
+		if size < 2 || i+2+size > len(data) {
+			break
+		}
+		segment := data[i+4 : i+2+size]
+		if marker == 0xE2 && len(segment) > 14 && bytes.HasPrefix(segment, []byte("ICC_PROFILE\x00")) {
+			chunks = append(chunks, chunk{segment[12], segment[14:]})
+		}
+		i += 2 + size
+	}
+	if len(chunks) == 0 {
+		return nil
+	}
+	sort.SliceStable(chunks, func(i, j int) bool { return chunks[i].seq < chunks[j].seq })
+	var profile []byte
+	for _, c := range chunks {
+		profile = append(profile, c.data...)
+	}
+	return profile
+}
+
+// pngICC returns the decompressed iCCP chunk of PNG data
+func pngICC(data []byte) []byte {
+	for i := 8; i+12 <= len(data); {
+		length := int(binary.BigEndian.Uint32(data[i:]))
+		typ := string(data[i+4 : i+8])
+		if length < 0 || i+12+length > len(data) || typ == "IDAT" {
+			return nil
+		}
+		if typ == "iCCP" {
+			chunk := data[i+8 : i+8+length]
+			name := bytes.IndexByte(chunk, 0)
+			if name < 0 || name+2 > len(chunk) || chunk[name+1] != 0 {
+				return nil
+			}
+			r, err := zlib.NewReader(bytes.NewReader(chunk[name+2:]))
+			if err != nil {
+				return nil
+			}
+			defer r.Close()
+			profile, err := io.ReadAll(io.LimitReader(r, maxICCSize))
+			if err != nil {
+				return nil
+			}
+			return profile
+		}
+		i += 12 + length
+	}
+	return nil
+}
+
+// parseICC reads the colorants and tone curves of a matrix/TRC RGB profile.
+// Other profiles (CMYK, grey, lookup tables) are not supported.
+func parseICC(profile []byte) (*iccTransform, error) {
+	if len(profile) < 132 {
+		return nil, errors.New("profile too short")
+	}
+	if space := string(profile[16:20]); space != "RGB " {
+		return nil, fmt.Errorf("unsupported colour space '%s'", space)
+	}
+	tags := make(map[string][]byte)
+	count := int(binary.BigEndian.Uint32(profile[128:]))
+	for i := 0; i < count; i++ {
+		entry := 132 + i*12
+		if entry+12 > len(profile) {
+			return nil, errors.New("truncated tag table")
+		}
+		offset := int(binary.BigEndian.Uint32(profile[entry+4:]))
+		size := int(binary.BigEndian.Uint32(profile[entry+8:]))
+		if offset < 0 || size < 0 || offset+size > len(profile) {
+			return nil, errors.New("tag outside the profile")
+		}
+		tags[string(profile[entry:entry+4])] = profile[offset : offset+size]
+	}
+
+	t := &iccTransform{}
+	var toD50 [3][3]float64
+	for c, names := range [3][2]string{{"rXYZ", "rTRC"}, {"gXYZ", "gTRC"}, {"bXYZ", "bTRC"}} {
+		xyz, err := readXYZ(tags[names[0]])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", names[0], err)
+		}
+		for row := range xyz {
+			toD50[row][c] = xyz[row]
+		}
+		curve, err := readCurve(tags[names[1]])
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", names[1], err)
+		}
+		for v := range t.linear[c] {
+			t.linear[c][v] = curve(float64(v) / 255)
+		}
+	}
+	for i := range 3 {
+		for j := range 3 {
+			for k := range 3 {
+				t.matrix[i][j] += d50ToSRGB[i][k] * toD50[k][j]
+			}
+		}
+	}
+	return t, nil
+}
+
+// readXYZ reads an XYZType tag
+func readXYZ(tag []byte) ([3]float64, error) {
+	if len(tag) < 20 || string(tag[:4]) != "XYZ " {
+		return [3]float64{}, errors.New("missing or invalid XYZ tag")
+	}
+	return [3]float64{s15Fixed16(tag[8:]), s15Fixed16(tag[12:]), s15Fixed16(tag[16:])}, nil
+}
+
+// readCurve reads a curveType or parametricCurveType tag as a function from
+// encoded values to linear light, both in [0, 1]
+func readCurve(tag []byte) (func(float64) float64, error) {
+	if len(tag) < 12 {
+		return nil, errors.New("missing or invalid tone curve")
+	}
+	switch string(tag[:4]) {
+	case "curv":
+		n := int(binary.BigEndian.Uint32(tag[8:]))
+		switch {
+		case n == 0:
+			return func(x float64) float64 { return x }, nil
+		case n == 1 && len(tag) >= 14:
+			gamma := float64(binary.BigEndian.Uint16(tag[12:])) / 256
+			return func(x float64) float64 { return math.Pow(x, gamma) }, nil
+		case n > 1 && len(tag) >= 12+2*n:
+			table := make([]float64, n)
+			for i := range table {
+				table[i] = float64(binary.BigEndian.Uint16(tag[12+2*i:])) / 65535
+			}
+			return func(x float64) float64 {
+				pos := x * float64(n-1)
+				i := min(int(pos), n-2)
+				return table[i] + (table[i+1]-table[i])*(pos-float64(i))
+			}, nil
+		}
+	case "para":
+		funcType := int(binary.BigEndian.Uint16(tag[8:]))
+		counts := []int{1, 3, 4, 5, 7}
+		if funcType >= len(counts) || len(tag) < 12+4*counts[funcType] {
+			break
+		}
+		var g [7]float64
+		for i := range counts[funcType] {
+			g[i] = s15Fixed16(tag[12+4*i:])
+		}
+		gamma, a, b, c, d, e, f := g[0], g[1], g[2], g[3], g[4], g[5], g[6]
+		return func(x float64) float64 {
+			var y float64
+			switch funcType {
+			case 0:
+				y = math.Pow(x, gamma)
+			case 1, 2:
+				if a != 0 && x >= -b/a {
+					y = math.Pow(a*x+b, gamma)
+				}
+				if funcType == 2 {
+					y += c
+				}
+			case 3, 4:
+				if x >= d {
+					y = math.Pow(a*x+b, gamma) + e
+				} else {
+					y = c*x + f
+				}
+			}
+			return math.Max(0, math.Min(1, y))
+		}, nil
+	}
+	return nil, errors.New("missing or invalid tone curve")
+}
+
+// s15Fixed16 reads a signed 15.16 fixed point number
+func s15Fixed16(b []byte) float64 {
+	return float64(int32(binary.BigEndian.Uint32(b))) / 65536
+}
+
+// isSRGB reports whether the profile matches sRGB closely enough that
+// converting would not change any pixel visibly
+func (t *iccTransform) isSRGB() bool {
+	for i := range 3 {
+		for j := range 3 {
+			want := 0.0
+			if i == j {
+				want = 1
+			}
+			if math.Abs(t.matrix[i][j]-want) > 0.01 {
+				return false
+			}
+		}
+		for v, lin := range t.linear[i] {
+			if math.Abs(lin-srgbToLinear(float64(v)/255)) > 0.005 {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// apply converts the pixels of img to sRGB in place
+func (t *iccTransform) apply(img *image.RGBA) {
+	for y := img.Rect.Min.Y; y < img.Rect.Max.Y; y++ {
+		row := img.Pix[img.PixOffset(img.Rect.Min.X, y):img.PixOffset(img.Rect.Max.X, y)]
+		for i := 0; i < len(row); i += 4 {
+			px := row[i : i+4 : i+4]
+			a := px[3]
+			if a == 0 {
+				continue
+			}
+			var in [3]float64
+			for c := range in {
+				v := px[c]
+				if a != 255 {
+					v = uint8(min(255, int(v)*255/int(a))) // Un-premultiply
+				}
+				in[c] = t.linear[c][v]
+			}
+			for c := range 3 {
+				lin := t.matrix[c][0]*in[0] + t.matrix[c][1]*in[1] + t.matrix[c][2]*in[2]
+				v := srgbEncode[int(math.Round(math.Max(0, math.Min(1, lin))*4095))]
+				if a != 255 {
+					v = uint8(int(v) * int(a) / 255)
+				}
+				px[c] = v
+			}
+		}
+	}
+}
+
+// colorTransform returns the conversion of the ICC profile embedded in data,
+// or nil when there is none, it is not supported or it already is sRGB
+func colorTransform(data []byte) *iccTransform {
+	profile := iccProfile(data)
+	if profile == nil {
+		return nil
+	}
+	t, err := parseICC(profile)
+	if err != nil || t.isSRGB() {
+		return nil
+	}
+	return t
+}
+
+// needsSRGB reports whether data is a CMYK/YCCK JPEG or carries an ICC
+// profile that must be converted before Excel shows the right colours
+func needsSRGB(data []byte) bool {
+	if cfg, format, err := image.DecodeConfig(bytes.NewReader(data)); err == nil && format == "jpeg" && cfg.ColorModel == color.CMYKModel {
+		return true
+	}
+	return colorTransform(data) != nil
+}
+
+// toSRGB converts a decoded CMYK image, or an image whose data carries a
+// wide-gamut ICC profile, to sRGB. CMYK pixels use the plain conversion of the
+// image/color package, as their profiles are lookup tables. It returns src and
+// false when nothing needs converting.
+func toSRGB(src image.Image, data []byte) (image.Image, bool) {
+	_, cmyk := src.(*image.CMYK)
+	var t *iccTransform
+	if !cmyk {
+		if t = colorTransform(data); t == nil {
+			return src, false
+		}
+	}
+	b := src.Bounds()
+	dst := image.NewRGBA(b)
+	draw.Draw(dst, b, src, b.Min, draw.Src)
+	if t != nil {
+		t.apply(dst)
+	}
+	return dst, true
+}
@@ -0,0 +1,268 @@
+package engine
+
+import (
+	"bytes"
+	"compress/zlib"
+	"encoding/binary"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"testing"
+)
+
+// D50 colorants of test profiles, columns r, g, b
+var (
+	srgbColorants = [3][3]float64{
+		{0.4360747, 0.3850649, 0.1430804},
+		{0.2225045, 0.7168786, 0.0606169},
+		{0.0139322, 0.0971045, 0.7141733},
+	}
+	adobeColorants = [3][3]float64{
+		{0.6097559, 0.2052401, 0.1492240},
+		{0.3111242, 0.6256560, 0.0632197},
+		{0.0194811, 0.0608902, 0.7448387},
+	}
+)
+
+func fixed16(v float64) []byte {
+	return binary.BigEndian.AppendUint32(nil, uint32(int32(math.Round(v*65536))))
+}
+
+// gammaCurve returns a curveType tag with a single gamma value
+func gammaCurve(gamma float64) []byte {
+	tag := append([]byte("curv\x00\x00\x00\x00"), 0, 0, 0, 1)
+	return binary.BigEndian.AppendUint16(tag, uint16(math.Round(gamma*256)))
+}
+
+// srgbCurve returns the sRGB tone curve as a parametricCurveType tag
+func srgbCurve() []byte {
+	tag := []byte("para\x00\x00\x00\x00\x00\x03\x00\x00")
+	for _, v := range []float64{2.4, 1 / 1.055, 0.055 / 1.055, 1 / 12.92, 0.04045} {
+		tag = append(tag, fixed16(v)...)
+	}
+	return tag
+}
+
+// testICC builds a matrix/TRC profile in the given colour space whose three
+// channels share one tone curve
+func testICC(space string, colorants [3][3]float64, curve []byte) []byte {
+	const dataStart = 132 + 6*12
+	var data []byte
+	var table []byte
+	addTag := func(sig string, offset, size int) {
+		table = append(table, sig...)
+		table = binary.BigEndian.AppendUint32(table, uint32(dataStart+offset))
+		table = binary.BigEndian.AppendUint32(table, uint32(size))
+	}
+	for c, sig := range []string{"rXYZ", "gXYZ", "bXYZ"} {
+		addTag(sig, len(data), 20)
+		data = append(data, "XYZ \x00\x00\x00\x00"...)
+		for row := range 3 {
+			data = append(data, fixed16(colorants[row][c])...)
+		}
+	}
+	for _, sig := range []string{"rTRC", "gTRC", "bTRC"} {
+		addTag(sig, len(data), len(curve))
+	}
+	data = append(data, curve...)
+
+	profile := make([]byte, 128)
+	binary.BigEndian.PutUint32(profile, uint32(dataStart+len(data)))
+	copy(profile[12:], "mntr")
+	copy(profile[16:], space)
+	copy(profile[20:], "XYZ ")
+	copy(profile[36:], "acsp")
+	profile = binary.BigEndian.AppendUint32(profile, 6)
+	profile = append(profile, table...)
+	return append(profile, data...)
+}
+
+// withICCProfile inserts profile into JPEG data as APP2 chunks of chunkSize bytes
+func withICCProfile(jpg, profile []byte, chunkSize int) []byte {
+	out := append([]byte{}, jpg[:2]...)
+	count := (len(profile) + chunkSize - 1) / chunkSize
+	for seq := 1; seq <= count; seq++ {
+		part := profile[(seq-1)*chunkSize : min(seq*chunkSize, len(profile))]
+		segment := append([]byte("ICC_PROFILE\x00"), byte(seq), byte(count))
+		segment = append(segment, part...)
+		out = append(out, 0xFF, 0xE2)
+		out = binary.BigEndian.AppendUint16(out, uint16(len(segment)+2))
+		out = append(out, segment...)
+	}
+	return append(out, jpg[2:]...)
+}
+
+// withPNGProfile inserts profile into PNG data as an iCCP chunk after IHDR
+func withPNGProfile(t *testing.T, pngData, profile []byte) []byte {
+	t.Helper()
+	var compressed bytes.Buffer
+	zw := zlib.NewWriter(&compressed)
+	_, _ = zw.Write(profile)
+	if err := zw.Close(); err != nil {
+		t.Fatal(err)
+	}
+	body := append([]byte("iCCP"), "test\x00\x00"...)
+	body = append(body, compressed.Bytes()...)
+	chunk := binary.BigEndian.AppendUint32(nil, uint32(len(body)-4))
+	chunk = append(chunk, body...)
+	chunk = binary.BigEndian.AppendUint32(chunk, crc32.ChecksumIEEE(body))
+
+	const ihdrEnd = 8 + 12 + 13
+	out := append([]byte{}, pngData[:ihdrEnd]...)
+	out = append(out, chunk...)
+	return append(out, pngData[ihdrEnd:]...)
+}
+
+// cmykJPEGHeader returns the markers of an 8x8 four-component JPEG up to the
+// start of scan, enough for image.DecodeConfig
+func cmykJPEGHeader() []byte {
+	header := []byte{0xFF, 0xD8, 0xFF, 0xC0, 0, 20, 8, 0, 8, 0, 8, 4}
+	for id := byte(1); id <= 4; id++ {
+		header = append(header, id, 0x11, 0)
+	}
+	return append(header, 0xFF, 0xDA, 0, 2)
+}
+
+func TestParseICC(t *testing.T) {
+	tests := []struct {
+		name     string
+		profile  []byte
+		wantErr  bool
+		wantSRGB bool
+	}{
+		{"sRGB", testICC("RGB ", srgbColorants, srgbCurve()), false, true},
+		{"Adobe RGB", testICC("RGB ", adobeColorants, gammaCurve(563.0/256)), false, false},
+		{"linear sRGB", testICC("RGB ", srgbColorants, gammaCurve(1)), false, false},
+		{"CMYK", testICC("CMYK", srgbColorants, srgbCurve()), true, false},
+		{"truncated", testICC("RGB ", srgbColorants, srgbCurve())[:140], true, false},
+		{"bad curve", testICC("RGB ", srgbColorants, []byte("curv\x00\x00\x00\x00")), true, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tr, err := parseICC(tt.profile)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseICC() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err == nil && tr.isSRGB() != tt.wantSRGB {
+				t.Errorf("isSRGB() = %v, want %v", tr.isSRGB(), tt.wantSRGB)
+			}
+		})
+	}
+}
+
+func TestICCTransform_Apply(t *testing.T) {
+	linear, _ := parseICC(testICC("RGB ", srgbColorants, gammaCurve(1)))
+	adobe, _ := parseICC(testICC("RGB ", adobeColorants, gammaCurve(563.0/256)))
+
+	tests := []struct {
+		name string
+		tr   *iccTransform
+		in   color.RGBA
+		want color.RGBA
+	}{
+		{"linear grey", linear, color.RGBA{128, 128, 128, 255}, color.RGBA{188, 188, 188, 255}},
+		{"linear black", linear, color.RGBA{0, 0, 0, 255}, color.RGBA{0, 0, 0, 255}},
+		{"premultiplied", linear, color.RGBA{64, 64, 64, 128}, color.RGBA{94, 94, 94, 128}},
+		{"Adobe white", adobe, color.RGBA{255, 255, 255, 255}, color.RGBA{255, 255, 255, 255}},
+		{"Adobe red", adobe, color.RGBA{128, 0, 0, 255}, color.RGBA{150, 0, 0, 255}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			img := image.NewRGBA(image.Rect(0, 0, 1, 1))
+			img.SetRGBA(0, 0, tt.in)
+			tt.tr.apply(img)
+			got := img.RGBAAt(0, 0)
+			for c, v := range []uint8{got.R, got.G, got.B, got.A} {
+				want := []uint8{tt.want.R, tt.want.G, tt.want.B, tt.want.A}[c]
+				if d := int(v) - int(want); d < -2 || d > 2 {
+					t.Fatalf("apply(%v) = %v, want %v", tt.in, got, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestICCProfile(t *testing.T) {
+	profile := testICC("RGB ", adobeColorants, gammaCurve(2.2))
+	jpg := encodeTestImage(t, FormatJPEG, 8, 8)
+	pngData := encodeTestImage(t, FormatPNG, 8, 8)
+
+	tests := []struct {
+		name string
+		data []byte
+		want []byte
+	}{
+		{"JPEG in one chunk", withICCProfile(jpg, profile, 1<<16-100), profile},
+		{"JPEG in several chunks", withICCProfile(jpg, profile, 50), profile},
+		{"PNG", withPNGProfile(t, pngData, profile), profile},
+		{"JPEG without profile", jpg, nil},
+		{"PNG without profile", pngData, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := iccProfile(tt.data); !bytes.Equal(got, tt.want) {
+				t.Errorf("iccProfile() returned %d bytes, want %d", len(got), len(tt.want))
+			}
+		})
+	}
+}
+
+func TestNeedsSRGB(t *testing.T) {
+	jpg := encodeTestImage(t, FormatJPEG, 8, 8)
+	tests := []struct {
+		name string
+		data []byte
+		want bool
+	}{
+		{"untagged", jpg, false},
+		{"sRGB profile", withICCProfile(jpg, testICC("RGB ", srgbColorants, srgbCurve()), 1000), false},
+		{"Adobe RGB profile", withICCProfile(jpg, testICC("RGB ", adobeColorants, gammaCurve(2.2)), 1000), true},
+		{"CMYK", cmykJPEGHeader(), true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := needsSRGB(tt.data); got != tt.want {
+				t.Errorf("needsSRGB() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestResampleOptions_ResampleConvertsColors(t *testing.T) {
+	img := image.NewRGBA(image.Rect(0, 0, 10, 10))
+	for i := range img.Pix {
+		img.Pix[i] = 128
+		if i%4 == 3 {
+			img.Pix[i] = 255
+		}
+	}
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, img)
+	tagged := withPNGProfile(t, buf.Bytes(), testICC("RGB ", srgbColorants, gammaCurve(1)))
+
+	tests := []struct {
+		name     string
+		opts     ResampleOptions
+		wantGrey uint8
+	}{
+		{"converted", ResampleOptions{}, 188},
+		{"kept", ResampleOptions{KeepColors: true}, 128},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := tt.opts.resample(tagged, ".png", 100, 100, true)
+			if err != nil {
+				t.Fatal(err)
+			}
+			out, _, err := image.Decode(bytes.NewReader(res.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if r, _, _, _ := out.At(5, 5).RGBA(); uint8(r>>8) != tt.wantGrey {
+				t.Errorf("grey = %d, want %d", r>>8, tt.wantGrey)
+			}
+		})
+	}
+}
@@ -0,0 +1,43 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Compatibility targets for Processor.Compatibility. Each target lists the
+// picture formats it displays; other images are transcoded to PNG or JPEG.
+// WebP is never stored as is, since the xlsx writer cannot embed it.
+const (
+	CompatModern      = "modern"      // Excel 2016 and later, including Microsoft 365 (default)
+	CompatLibreOffice = "libreoffice" // LibreOffice Calc
+)
+
+// compatFormats lists the extensions each compatibility target displays.
+// Excel 2016 and Microsoft 365 show the same raster formats, so they share a
+// target; WebP is missing since it cannot be embedded at all.
+var compatFormats = map[string]map[string]bool{
+	CompatModern:      {".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true, ".tif": true, ".tiff": true},
+	CompatLibreOffice: {".png": true, ".jpg": true, ".jpeg": true, ".gif": true, ".bmp": true},
+}
+
+// validateCompatibility checks the configured compatibility target
+func (p *Processor) validateCompatibility() error {
+	if p.Compatibility == "" {
+		return nil
+	}
+	if _, ok := compatFormats[p.Compatibility]; !ok {
+		return fmt.Errorf("unknown compatibility target '%s'", p.Compatibility)
+	}
+	return nil
+}
+
+// displayable reports whether pictures with extension ext can be embedded
+// unchanged for the compatibility target
+func (p *Processor) displayable(ext string) bool {
+	target := p.Compatibility
+	if target == "" {
+		target = CompatModern
+	}
+	return compatFormats[target][strings.ToLower(ext)]
+}
@@ -0,0 +1,100 @@
+package engine
+
+import (
+	"context"
+	"encoding/base64"
+	"image"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/image/bmp"
+	"golang.org/x/image/tiff"
+)
+
+// tinyWebP is a 1x1 lossless WebP image
+const tinyWebP = "UklGRhoAAABXRUJQVlA4TA0AAAAvAAAAEAcQERGIiP4HAA=="
+
+func TestProcessor_Displayable(t *testing.T) {
+	tests := []struct {
+		compat string
+		ext    string
+		want   bool
+	}{
+		{"", ".png", true},
+		{"", ".WEBP", false},
+		{CompatModern, ".tiff", true},
+		{CompatModern, ".JPG", true},
+		{CompatModern, ".webp", false},
+		{CompatLibreOffice, ".tif", false},
+		{CompatLibreOffice, ".bmp", true},
+	}
+	for _, tt := range tests {
+		p := &Processor{Compatibility: tt.compat}
+		if got := p.displayable(tt.ext); got != tt.want {
+			t.Errorf("displayable(%q) with %q = %v, want %v", tt.ext, tt.compat, got, tt.want)
+		}
+	}
+
+	if err := (&Processor{Compatibility: "excel97"}).validateCompatibility(); err == nil {
+		t.Error("validateCompatibility() accepted an unknown target")
+	}
+}
+
+func TestProcessor_RunCompatibility(t *testing.T) {
+	tests := []struct {
+		compat string
+		want   map[string]string // Cell -> embedded extension
+	}{
+		{CompatModern, map[string]string{"B1": ".png", "B2": ".tiff", "B3": ".bmp"}},
+		{CompatLibreOffice, map[string]string{"B1": ".png", "B2": ".png", "B3": ".bmp"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.compat, func(t *testing.T) {
+			tempDir := t.TempDir()
+			excelPath := filepath.Join(tempDir, "test.xlsx")
+			imageDir := filepath.Join(tempDir, "images")
+			_ = os.Mkdir(imageDir, 0755)
+
+			if err := createDummyExcel(excelPath, "Sheet1", "A", []string{"P001", "P002", "P003"}); err != nil {
+				t.Fatal(err)
+			}
+			webp, _ := base64.StdEncoding.DecodeString(tinyWebP)
+			_ = os.WriteFile(filepath.Join(imageDir, "P001.webp"), webp, 0644)
+			img := image.NewRGBA(image.Rect(0, 0, 20, 20))
+			tf, _ := os.Create(filepath.Join(imageDir, "P002.tif"))
+			_ = tiff.Encode(tf, img, nil)
+			tf.Close()
+			bf, _ := os.Create(filepath.Join(imageDir, "P003.bmp"))
+			_ = bmp.Encode(bf, img)
+			bf.Close()
+
+			p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 2, 100, 20)
+			p.Compatibility = tt.compat
+			p.Resample.KeepOriginal = true
+			if err := p.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			if p.ProcessedCount != 3 {
+				t.Fatalf("expected 3 inserted pictures, got %d (report: %+v)", p.ProcessedCount, p.Report.Rows)
+			}
+
+			f, err := excelize.OpenFile(p.OutputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			for cell, ext := range tt.want {
+				pics, err := f.GetPictures("Sheet1", cell)
+				if err != nil || len(pics) != 1 {
+					t.Fatalf("expected one picture in %s, got %d (err: %v)", cell, len(pics), err)
+				}
+				if pics[0].Extension != ext {
+					t.Errorf("%s embedded as %s, want %s", cell, pics[0].Extension, ext)
+				}
+			}
+		})
+	}
+}
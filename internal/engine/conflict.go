@@ -0,0 +1,119 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Conflict policies for Processor.ConflictPolicy, applied when one code has
+// several files in the same folder, e.g. P001.png and P001.jpg
+const (
+	ConflictExtension  = "extension"  // Prefer the earliest extension in ExtensionOrder (default)
+	ConflictResolution = "resolution" // Prefer the largest pixel count
+	ConflictNewest     = "newest"     // Prefer the most recently modified file
+	ConflictFail       = "fail"       // Abort the run and report the conflicts
+)
+
+// DefaultExtensionOrder is used by ConflictExtension when ExtensionOrder is empty
+var DefaultExtensionOrder = []string{".png", ".jpg", ".jpeg", ".webp", ".gif", ".bmp", ".tif", ".tiff"}
+
+// Conflict records which file was used when several files competed for one image slot
+type Conflict struct {
+	Name       string   `json:"name"`
+	Chosen     string   `json:"chosen"`
+	Candidates []string `json:"candidates"`
+	Policy     string   `json:"policy"`
+}
+
+// validateConflictPolicy checks the configured conflict policy
+func (p *Processor) validateConflictPolicy() error {
+	switch p.ConflictPolicy {
+	case "", ConflictExtension, ConflictResolution, ConflictNewest, ConflictFail:
+		return nil
+	}
+	return fmt.Errorf("invalid conflict policy '%s'", p.ConflictPolicy)
+}
+
+// resolveConflict picks one file among candidates that share a name and
+// folder. Ties are broken by extension order, then by path, so the result
+// never depends on directory listing order.
+func (p *Processor) resolveConflict(candidates []imageFile) imageFile {
+	policy := p.ConflictPolicy
+	if policy == "" || policy == ConflictFail {
+		policy = ConflictExtension
+	}
+
+	order := p.ExtensionOrder
+	if len(order) == 0 {
+		order = DefaultExtensionOrder
+	}
+	extRank := func(f imageFile) int {
+		ext := strings.ToLower(filepath.Ext(f.Rel))
+		for i, e := range order {
+			if strings.ToLower(e) == ext || "."+strings.ToLower(e) == ext {
+				return i
+			}
+		}
+		return len(order)
+	}
+
+	// Secondary keys for the resolution and newest policies
+	score := make(map[string]int64, len(candidates))
+	for _, f := range candidates {
+		path := filepath.Join(p.ImageDir, f.Rel)
+		switch policy {
+		case ConflictResolution:
+			if cfg, err := decodeConfigFile(path); err == nil {
+				score[f.Rel] = int64(cfg.Width) * int64(cfg.Height)
+			}
+		case ConflictNewest:
+			if info, err := os.Stat(path); err == nil {
+				score[f.Rel] = info.ModTime().UnixNano()
+			}
+		}
+	}
+
+	sorted := append([]imageFile{}, candidates...)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		a, b := sorted[i], sorted[j]
+		if score[a.Rel] != score[b.Rel] {
+			return score[a.Rel] > score[b.Rel]
+		}
+		if ra, rb := extRank(a), extRank(b); ra != rb {
+			return ra < rb
+		}
+		return a.Rel < b.Rel
+	})
+	return sorted[0]
+}
+
+// decodeConfigFile reads the displayed dimensions of an image file, taking
+// the EXIF orientation into account
+func decodeConfigFile(path string) (image.Config, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return image.Config{}, err
+	}
+	defer f.Close()
+
+	head := make([]byte, exifHeaderSize)
+	n, _ := io.ReadFull(f, head)
+	cfg, _, err := image.DecodeConfig(io.MultiReader(bytes.NewReader(head[:n]), f))
+	if err != nil {
+		return cfg, err
+	}
+	cfg.Width, cfg.Height = orientedSize(cfg.Width, cfg.Height, exifOrientation(head[:n]))
+	return cfg, nil
+}
+
+// conflictError summarizes the conflicts that stopped a run under ConflictFail
+func conflictError(conflicts []Conflict) error {
+	first := conflicts[0]
+	return fmt.Errorf("%d image conflict(s), e.g. %s: %s", len(conflicts), first.Name, strings.Join(first.Candidates, ", "))
+}
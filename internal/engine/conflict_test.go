@@ -0,0 +1,88 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestProcessor_ResolveConflict(t *testing.T) {
+	root := t.TempDir()
+	_ = createDummyImage(filepath.Join(root, "P001.png"), 10, 10)
+	_ = createDummyImage(filepath.Join(root, "P001.jpg"), 40, 40)
+	_ = createDummyImage(filepath.Join(root, "P001.webp"), 20, 20)
+
+	// Make the webp file the newest one
+	old := time.Now().Add(-time.Hour)
+	_ = os.Chtimes(filepath.Join(root, "P001.png"), old, old)
+	_ = os.Chtimes(filepath.Join(root, "P001.jpg"), old, old)
+
+	candidates := []imageFile{
+		{Rel: "P001.jpg", Stem: "P001"},
+		{Rel: "P001.png", Stem: "P001"},
+		{Rel: "P001.webp", Stem: "P001"},
+	}
+
+	tests := []struct {
+		name   string
+		policy string
+		order  []string
+		want   string
+	}{
+		{"default", "", nil, "P001.png"},
+		{"extension order", ConflictExtension, []string{"webp", ".jpg"}, "P001.webp"},
+		{"resolution", ConflictResolution, nil, "P001.jpg"},
+		{"newest", ConflictNewest, nil, "P001.webp"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Processor{ImageDir: root, ConflictPolicy: tt.policy, ExtensionOrder: tt.order}
+			// Resolution must not depend on the input order
+			for _, in := range [][]imageFile{candidates, {candidates[2], candidates[1], candidates[0]}} {
+				if got := p.resolveConflict(in); got.Rel != tt.want {
+					t.Errorf("resolveConflict() = %s, want %s", got.Rel, tt.want)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessor_RunConflicts(t *testing.T) {
+	tempDir := t.TempDir()
+	excelPath := filepath.Join(tempDir, "test.xlsx")
+	imageDir := filepath.Join(tempDir, "images")
+	_ = os.Mkdir(imageDir, 0755)
+
+	if err := createDummyExcel(excelPath, "Sheet1", "A", []string{"P001"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = createDummyImage(filepath.Join(imageDir, "P001.png"), 10, 10)
+	_ = createDummyImage(filepath.Join(imageDir, "P001.jpg"), 10, 10)
+
+	p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 1, 100, 20)
+	p.ExtensionOrder = []string{".jpg"}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+	if len(p.Conflicts) != 1 || p.Conflicts[0].Chosen != "P001.jpg" || len(p.Conflicts[0].Candidates) != 2 {
+		t.Errorf("unexpected conflicts: %+v", p.Conflicts)
+	}
+	if len(p.Matches) != 1 || p.Matches[0].File != "P001.jpg" {
+		t.Errorf("expected P001.jpg to be used, got %+v", p.Matches)
+	}
+
+	p = NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 1, 100, 20)
+	p.ConflictPolicy = ConflictFail
+	if err := p.Run(context.Background()); err == nil {
+		t.Error("expected Run() to fail with the fail policy")
+	}
+
+	p = NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 1, 100, 20)
+	p.ConflictPolicy = "coin-flip"
+	if err := p.Run(context.Background()); err == nil {
+		t.Error("expected Run() to reject an unknown policy")
+	}
+}
@@ -0,0 +1,141 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"math"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// withExifOrientation inserts an APP1 EXIF block holding only the Orientation
+// tag right after the JPEG start marker
+func withExifOrientation(jpg []byte, orientation int, order binary.ByteOrder) []byte {
+	tiff := make([]byte, 26)
+	if order == binary.LittleEndian {
+		copy(tiff, "II")
+	} else {
+		copy(tiff, "MM")
+	}
+	order.PutUint16(tiff[2:], 42)
+	order.PutUint32(tiff[4:], 8) // First IFD offset
+	order.PutUint16(tiff[8:], 1) // Entry count
+	order.PutUint16(tiff[10:], 0x0112)
+	order.PutUint16(tiff[12:], 3) // SHORT
+	order.PutUint32(tiff[14:], 1)
+	order.PutUint16(tiff[18:], uint16(orientation))
+
+	segment := append([]byte("Exif\x00\x00"), tiff...)
+	app1 := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(app1[2:], uint16(len(segment)+2))
+	app1 = append(app1, segment...)
+
+	out := append([]byte{}, jpg[:2]...)
+	out = append(out, app1...)
+	return append(out, jpg[2:]...)
+}
+
+// orientationFixture returns a JPEG whose displayed image is 40x20 with a red
+// top-left corner, stored the way a camera would for the given orientation
+func orientationFixture(t *testing.T, orientation int) []byte {
+	t.Helper()
+	// Corner of the stored pixels that ends up top-left when displayed
+	corners := map[int]string{1: "tl", 2: "tr", 3: "br", 4: "bl", 5: "tl", 6: "bl", 7: "br", 8: "tr"}
+	w, h := orientedSize(40, 20, orientation)
+
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			left, top := x < 10, y < 10
+			right, bottom := x >= w-10, y >= h-10
+			var marked bool
+			switch corners[orientation] {
+			case "tl":
+				marked = left && top
+			case "tr":
+				marked = right && top
+			case "bl":
+				marked = left && bottom
+			case "br":
+				marked = right && bottom
+			}
+			if marked {
+				img.Set(x, y, color.RGBA{255, 0, 0, 255})
+			} else {
+				img.Set(x, y, color.RGBA{0, 0, 255, 255})
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, img, &jpeg.Options{Quality: 100}); err != nil {
+		t.Fatal(err)
+	}
+	var order binary.ByteOrder = binary.BigEndian
+	if orientation%2 == 0 {
+		order = binary.LittleEndian
+	}
+	return withExifOrientation(buf.Bytes(), orientation, order)
+}
+
+func TestExifOrientation_AllOrientations(t *testing.T) {
+	tempDir := t.TempDir()
+	p := &Processor{}
+
+	for orientation := 1; orientation <= 8; orientation++ {
+		data := orientationFixture(t, orientation)
+		if got := exifOrientation(data); got != orientation {
+			t.Errorf("exifOrientation() = %d, want %d", got, orientation)
+		}
+
+		path := filepath.Join(tempDir, "photo.jpg")
+		_ = os.WriteFile(path, data, 0644)
+		if _, w, h, err := p.loadImageData(path); err != nil || w != 40 || h != 20 {
+			t.Errorf("orientation %d: loadImageData() size %dx%d (err: %v), want 40x20", orientation, w, h, err)
+		}
+		if cfg, err := decodeConfigFile(path); err != nil || cfg.Width != 40 || cfg.Height != 20 {
+			t.Errorf("orientation %d: decodeConfigFile() size %dx%d (err: %v), want 40x20", orientation, cfg.Width, cfg.Height, err)
+		}
+
+		res, err := ResampleOptions{Quality: 100}.resample(data, ".jpg", math.Inf(1), math.Inf(1), true)
+		if err != nil {
+			t.Fatalf("orientation %d: resample() error: %v", orientation, err)
+		}
+		img, err := jpeg.Decode(bytes.NewReader(res.data))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := img.Bounds(); b.Dx() != 40 || b.Dy() != 20 {
+			t.Errorf("orientation %d: upright image is %dx%d, want 40x20", orientation, b.Dx(), b.Dy())
+		}
+		if r, _, b, _ := img.At(2, 2).RGBA(); r < 0xC000 || b > 0x4000 {
+			t.Errorf("orientation %d: top-left pixel is not red", orientation)
+		}
+		if r, _, b, _ := img.At(37, 17).RGBA(); r > 0x4000 || b < 0xC000 {
+			t.Errorf("orientation %d: bottom-right pixel is not blue", orientation)
+		}
+	}
+}
+
+func TestExifOrientation_Invalid(t *testing.T) {
+	valid := orientationFixture(t, 6)
+	tests := []struct {
+		name string
+		data []byte
+	}{
+		{"empty", nil},
+		{"not a jpeg", []byte("\x89PNG\r\n\x1a\n")},
+		{"no exif", encodeTestImage(t, FormatJPEG, 4, 4)},
+		{"truncated segment", valid[:12]},
+		{"out of range", withExifOrientation(valid[:2], 9, binary.BigEndian)},
+	}
+	for _, tt := range tests {
+		if got := exifOrientation(tt.data); got != 1 {
+			t.Errorf("%s: exifOrientation() = %d, want 1", tt.name, got)
+		}
+	}
+}
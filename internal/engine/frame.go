@@ -0,0 +1,94 @@
+package engine
+
+import (
+	"fmt"
+	"image"
+	"image/color"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// DefaultTrimTolerance is used when ResampleOptions.TrimTolerance is unset
+const DefaultTrimTolerance = 16
+
+// trimBounds returns the bounds of img without its near-uniform borders. The
+// top-left pixel gives the border colour; a pixel belongs to the border when
+// every channel is within tolerance of it. Uniform images are not trimmed.
+func trimBounds(img image.Image, tolerance int) image.Rectangle {
+	b := img.Bounds()
+	ref := color.RGBAModel.Convert(img.At(b.Min.X, b.Min.Y)).(color.RGBA)
+	border := func(x, y int) bool {
+		c := color.RGBAModel.Convert(img.At(x, y)).(color.RGBA)
+		return near(c.R, ref.R, tolerance) && near(c.G, ref.G, tolerance) &&
+			near(c.B, ref.B, tolerance) && near(c.A, ref.A, tolerance)
+	}
+	rowIsBorder := func(y int) bool {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if !border(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+	colIsBorder := func(x, minY, maxY int) bool {
+		for y := minY; y < maxY; y++ {
+			if !border(x, y) {
+				return false
+			}
+		}
+		return true
+	}
+
+	top := b.Min.Y
+	for top < b.Max.Y && rowIsBorder(top) {
+		top++
+	}
+	if top == b.Max.Y {
+		return b
+	}
+	bottom := b.Max.Y
+	for bottom > top && rowIsBorder(bottom-1) {
+		bottom--
+	}
+	left := b.Min.X
+	for left < b.Max.X && colIsBorder(left, top, bottom) {
+		left++
+	}
+	right := b.Max.X
+	for right > left && colIsBorder(right-1, top, bottom) {
+		right--
+	}
+	return image.Rect(left, top, right, bottom)
+}
+
+// near reports whether two channel values differ by at most tolerance
+func near(a, b uint8, tolerance int) bool {
+	d := int(a) - int(b)
+	return d >= -tolerance && d <= tolerance
+}
+
+// padToAspect grows a width x height image to the given width/height aspect
+// ratio, keeping the whole image
+func padToAspect(width, height int, aspect float64) (int, int) {
+	if aspect <= 0 || math.IsInf(aspect, 0) || math.IsNaN(aspect) {
+		return width, height
+	}
+	if float64(width)/float64(height) < aspect {
+		return max(width, int(math.Round(float64(height)*aspect))), height
+	}
+	return width, max(height, int(math.Round(float64(width)/aspect)))
+}
+
+// parseHexColor parses a #RRGGBB colour
+func parseHexColor(s string) (color.RGBA, error) {
+	hex := strings.TrimPrefix(strings.TrimSpace(s), "#")
+	if len(hex) != 6 {
+		return color.RGBA{}, fmt.Errorf("invalid colour '%s', expected #RRGGBB", s)
+	}
+	v, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return color.RGBA{}, fmt.Errorf("invalid colour '%s', expected #RRGGBB", s)
+	}
+	return color.RGBA{R: uint8(v >> 16), G: uint8(v >> 8), B: uint8(v), A: 255}, nil
+}
@@ -0,0 +1,143 @@
+package engine
+
+import (
+	"bytes"
+	"image"
+	"image/color"
+	"image/png"
+	"testing"
+)
+
+// framedImage returns a 100x100 image with a black 20x40 product at (30,20)
+// on a background that varies by up to noise per channel
+func framedImage(noise uint8) *image.RGBA {
+	img := image.NewRGBA(image.Rect(0, 0, 100, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 100; x++ {
+			v := 255 - noise*uint8((x+y)%2)
+			img.Set(x, y, color.RGBA{v, v, v, 255})
+		}
+	}
+	for y := 20; y < 60; y++ {
+		for x := 30; x < 50; x++ {
+			img.Set(x, y, color.RGBA{0, 0, 0, 255})
+		}
+	}
+	return img
+}
+
+func TestTrimBounds(t *testing.T) {
+	tests := []struct {
+		name      string
+		img       image.Image
+		tolerance int
+		want      image.Rectangle
+	}{
+		{"clean border", framedImage(0), 0, image.Rect(30, 20, 50, 60)},
+		{"noisy border within tolerance", framedImage(10), 16, image.Rect(30, 20, 50, 60)},
+		{"noisy border above tolerance", framedImage(10), 5, image.Rect(0, 0, 100, 100)},
+		{"uniform image", image.NewGray(image.Rect(0, 0, 8, 8)), 0, image.Rect(0, 0, 8, 8)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := trimBounds(tt.img, tt.tolerance); got != tt.want {
+				t.Errorf("trimBounds() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPadToAspect(t *testing.T) {
+	tests := []struct {
+		w, h         int
+		aspect       float64
+		wantW, wantH int
+	}{
+		{20, 40, 2, 80, 40},
+		{100, 10, 2, 100, 50},
+		{50, 25, 2, 50, 25},
+		{50, 25, 0, 50, 25},
+	}
+	for _, tt := range tests {
+		if w, h := padToAspect(tt.w, tt.h, tt.aspect); w != tt.wantW || h != tt.wantH {
+			t.Errorf("padToAspect(%d, %d, %g) = %dx%d, want %dx%d", tt.w, tt.h, tt.aspect, w, h, tt.wantW, tt.wantH)
+		}
+	}
+}
+
+func TestResampleOptions_TrimAndPad(t *testing.T) {
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, framedImage(0))
+
+	tests := []struct {
+		name         string
+		opts         ResampleOptions
+		wantW, wantH int
+		edge         color.RGBA // Pixel at the middle of the left edge
+	}{
+		{"trim only", ResampleOptions{Trim: true}, 20, 40, color.RGBA{0, 0, 0, 255}},
+		{"trim and pad", ResampleOptions{Trim: true, Pad: true}, 80, 40, color.RGBA{255, 255, 255, 255}},
+		{"pad with colour", ResampleOptions{Trim: true, Pad: true, Background: "#ff0000"}, 80, 40, color.RGBA{255, 0, 0, 255}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := tt.opts.resample(buf.Bytes(), ".png", 130, 65, true)
+			if err != nil {
+				t.Fatalf("resample() error: %v", err)
+			}
+			img, err := png.Decode(bytes.NewReader(res.data))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if b := img.Bounds(); b.Dx() != tt.wantW || b.Dy() != tt.wantH || res.width != tt.wantW || res.height != tt.wantH {
+				t.Fatalf("resample() = %dx%d, want %dx%d", b.Dx(), b.Dy(), tt.wantW, tt.wantH)
+			}
+			if got := color.RGBAModel.Convert(img.At(0, tt.wantH/2)); got != tt.edge {
+				t.Errorf("left edge = %v, want %v", got, tt.edge)
+			}
+			if got := color.RGBAModel.Convert(img.At(tt.wantW/2, tt.wantH/2)); got != (color.RGBA{0, 0, 0, 255}) {
+				t.Errorf("centre = %v, want black", got)
+			}
+		})
+	}
+
+	if err := (ResampleOptions{Background: "red"}).validate(); err == nil {
+		t.Error("validate() accepted an invalid background colour")
+	}
+	if err := (ResampleOptions{TrimTolerance: ptr(300)}).validate(); err == nil {
+		t.Error("validate() accepted an out of range tolerance")
+	}
+}
+
+func TestResampleOptions_TrimTolerance(t *testing.T) {
+	var buf bytes.Buffer
+	_ = png.Encode(&buf, framedImage(10))
+
+	tests := []struct {
+		name      string
+		tolerance *int
+		wantW     int
+	}{
+		{"default trims the noisy border", nil, 20},
+		{"zero keeps the noisy border", ptr(0), 65},
+		{"explicit default", ptr(DefaultTrimTolerance), 20},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res, err := ResampleOptions{Trim: true, TrimTolerance: tt.tolerance}.resample(buf.Bytes(), ".png", 130, 65, true)
+			if err != nil {
+				t.Fatalf("resample() error: %v", err)
+			}
+			if res.width != tt.wantW {
+				t.Errorf("resample() width = %d, want %d", res.width, tt.wantW)
+			}
+		})
+	}
+
+	key := func(tolerance *int) string {
+		return cacheKey(buf.Bytes(), ".png", 130, 65, true, ResampleOptions{Trim: true, TrimTolerance: tolerance})
+	}
+	if key(nil) != key(ptr(DefaultTrimTolerance)) || key(nil) == key(ptr(0)) {
+		t.Error("cache keys do not follow the effective tolerance")
+	}
+}
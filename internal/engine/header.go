@@ -0,0 +1,98 @@
+package engine
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+	"golang.org/x/text/unicode/norm"
+)
+
+// headerColumns is the outcome of resolving columns by header text
+type headerColumns struct {
+	CodeCol     string
+	ImageCol    string
+	CreateImage bool // ImageCol is a new column whose header must be written
+}
+
+// headerRowFor returns the header row of a sheet, defaulting to row 1 when
+// its columns are selected by header text
+func (p *Processor) headerRowFor(t SheetTarget) int {
+	if p.HeaderRow <= 0 && (t.CodeHeader != "" || t.ImageHeader != "") {
+		return 1
+	}
+	return p.HeaderRow
+}
+
+// resolveHeaderColumns finds the code and image columns of a sheet by their
+// header text in headerRow. Columns without a header name keep their
+// configured letter. A missing image header is placed in the first free
+// column after the last header cell.
+func (p *Processor) resolveHeaderColumns(t SheetTarget, headerRow int) (headerColumns, error) {
+	cols := headerColumns{CodeCol: t.CodeCol, ImageCol: t.ImageCol}
+	if t.CodeHeader == "" && t.ImageHeader == "" {
+		return cols, nil
+	}
+
+	headers, err := p.readRow(t.Name, headerRow)
+	if err != nil {
+		return cols, fmt.Errorf("failed to read header row %d: %w", headerRow, err)
+	}
+
+	find := func(name string) int {
+		for i, cell := range headers {
+			if sameHeader(cell, name) {
+				return i + 1
+			}
+		}
+		return 0
+	}
+
+	if t.CodeHeader != "" {
+		idx := find(t.CodeHeader)
+		if idx == 0 {
+			return cols, fmt.Errorf("code column header '%s' not found in row %d of sheet '%s'", t.CodeHeader, headerRow, t.Name)
+		}
+		if cols.CodeCol, err = excelize.ColumnNumberToName(idx); err != nil {
+			return cols, err
+		}
+	}
+
+	if t.ImageHeader != "" {
+		idx := find(t.ImageHeader)
+		if idx == 0 {
+			// Append after the last non-empty header cell
+			idx = len(headers) + 1
+			for idx > 1 && strings.TrimSpace(headers[idx-2]) == "" {
+				idx--
+			}
+			cols.CreateImage = true
+		}
+		if cols.ImageCol, err = excelize.ColumnNumberToName(idx); err != nil {
+			return cols, err
+		}
+	}
+	return cols, nil
+}
+
+// readRow returns the cell values of a single row using the streaming iterator
+func (p *Processor) readRow(sheet string, row int) ([]string, error) {
+	rows, err := p.f.Rows(sheet)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	for i := 1; rows.Next(); i++ {
+		if i == row {
+			return rows.Columns()
+		}
+	}
+	return nil, rows.Error()
+}
+
+// sameHeader compares header texts ignoring case, surrounding spaces and
+// Unicode normalization form
+func sameHeader(a, b string) bool {
+	return strings.EqualFold(norm.NFC.String(strings.TrimSpace(a)), norm.NFC.String(strings.TrimSpace(b)))
+}
@@ -0,0 +1,111 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// createHeaderExcel writes a sheet whose first row holds headers
+func createHeaderExcel(t *testing.T, path string, rows [][]any) {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		if err := f.SetSheetRow("Sheet1", cell, &row); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := f.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcessor_ResolveHeaderColumns(t *testing.T) {
+	excelPath := filepath.Join(t.TempDir(), "test.xlsx")
+	createHeaderExcel(t, excelPath, [][]any{
+		{"Name", " sku ", "Price", "Ảnh"}, // "Ảnh" in decomposed form
+	})
+
+	f, err := excelize.OpenFile(excelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	p := &Processor{f: f}
+
+	tests := []struct {
+		name        string
+		codeHeader  string
+		imageHeader string
+		want        headerColumns
+		wantErr     bool
+	}{
+		{"no headers", "", "", headerColumns{CodeCol: "A", ImageCol: "F"}, false},
+		{"both found", "SKU", "\u1ea2nh", headerColumns{CodeCol: "B", ImageCol: "D"}, false},
+		{"image created", "SKU", "Photo", headerColumns{CodeCol: "B", ImageCol: "E", CreateImage: true}, false},
+		{"code missing", "Barcode", "", headerColumns{}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			target := SheetTarget{Name: "Sheet1", CodeCol: "A", ImageCol: "F", CodeHeader: tt.codeHeader, ImageHeader: tt.imageHeader}
+			got, err := p.resolveHeaderColumns(target, 1)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("resolveHeaderColumns() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("resolveHeaderColumns() = %+v, want %+v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessor_RunWithHeaders(t *testing.T) {
+	tempDir := t.TempDir()
+	excelPath := filepath.Join(tempDir, "test.xlsx")
+	imageDir := filepath.Join(tempDir, "images")
+	_ = os.Mkdir(imageDir, 0755)
+
+	createHeaderExcel(t, excelPath, [][]any{
+		{"Name", "SKU"},
+		{"Shirt", "P001"},
+		{"Shoes", "P002"},
+	})
+	// An image named after the header must not be matched
+	_ = createDummyImage(filepath.Join(imageDir, "SKU.png"), 10, 10)
+	_ = createDummyImage(filepath.Join(imageDir, "P001.png"), 10, 10)
+
+	p := NewProcessor(excelPath, imageDir, "A", "F", "Sheet1", 1, 100, 20)
+	p.CodeHeader = "sku"
+	p.ImageHeader = "Image"
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if stat := p.SheetStats[0]; stat.CodeCol != "B" || stat.ImageCol != "C" {
+		t.Errorf("expected columns B/C, got %s/%s", stat.CodeCol, stat.ImageCol)
+	}
+	if p.ProcessedCount != 1 || len(p.MissingCodes) != 1 || p.MissingCodes[0] != "P002" {
+		t.Errorf("unexpected result: processed %d, missing %v", p.ProcessedCount, p.MissingCodes)
+	}
+
+	f, err := excelize.OpenFile(p.OutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	if v, _ := f.GetCellValue("Sheet1", "C1"); v != "Image" {
+		t.Errorf("expected created header 'Image' in C1, got %q", v)
+	}
+	if pics, _ := f.GetPictures("Sheet1", "C2"); len(pics) != 1 {
+		t.Errorf("expected a picture in C2, got %d", len(pics))
+	}
+	if pics, _ := f.GetPictures("Sheet1", "C1"); len(pics) != 0 {
+		t.Errorf("expected no picture in the header row, got %d", len(pics))
+	}
+}
@@ -0,0 +1,256 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Values of the status column, see InfoColumns.Status
+const (
+	InfoOK      = "OK"      // The row got its picture, or already had one
+	InfoMissing = "MISSING" // No image matches the code
+	InfoError   = "ERROR"   // An image was found but could not be placed
+)
+
+// Header labels written above empty info columns when the sheet has a header row
+var infoHeaders = map[string]string{
+	"status": "Image Status",
+	"file":   "Image File",
+	"dims":   "Image Size (px)",
+	"size":   "File Size (bytes)",
+	"link":   "Image Link",
+}
+
+// InfoColumns names the columns Run fills for every row with a product code,
+// so the sheet can be filtered by outcome. Empty columns are not written.
+type InfoColumns struct {
+	Status           string // InfoOK, InfoMissing or InfoError
+	File             string // Matched file relative to the image directory
+	Dims             string // Original pixel size, e.g. "800x600"
+	Size             string // Size of the source file in bytes
+	Link             string // Hyperlink to the source file
+	HighlightMissing bool   // Fill missing rows red with conditional formatting (needs Status)
+}
+
+// validate checks the info columns
+func (o InfoColumns) validate() error {
+	for _, col := range o.columns() {
+		if _, err := excelize.ColumnNameToNumber(col[1]); err != nil {
+			return fmt.Errorf("invalid %s column: %w", col[0], err)
+		}
+	}
+	if o.HighlightMissing && o.Status == "" {
+		return errors.New("highlighting missing rows needs a status column")
+	}
+	return nil
+}
+
+// columns lists the configured columns as kind and column name pairs
+func (o InfoColumns) columns() [][2]string {
+	var cols [][2]string
+	for _, c := range [][2]string{{"status", o.Status}, {"file", o.File}, {"dims", o.Dims}, {"size", o.Size}, {"link", o.Link}} {
+		if c[1] != "" {
+			cols = append(cols, [2]string{c[0], strings.ToUpper(c[1])})
+		}
+	}
+	return cols
+}
+
+// rowInfo is the outcome of a row for the info columns
+type rowInfo struct {
+	status string
+	main   ReportRow // Report row of the main image
+}
+
+// writeInfo fills the info columns of every row with a product code from
+// the report rows of the run
+func (p *Processor) writeInfo() error {
+	cols := p.Info.columns()
+	if len(cols) == 0 {
+		return nil
+	}
+
+	rows := make(map[RowRef]*rowInfo)
+	for _, r := range p.Report.Rows {
+		ref := RowRef{Sheet: r.Sheet, Row: r.Row}
+		ri := rows[ref]
+		if ri == nil {
+			ri = &rowInfo{status: InfoMissing}
+			rows[ref] = ri
+		}
+		switch r.Status {
+		case StatusInserted, StatusSkipped:
+			if ri.status != InfoError {
+				ri.status = InfoOK
+			}
+		case StatusDecodeError, StatusInsertError:
+			ri.status = InfoError
+		}
+		if r.Status != StatusMissing && r.Slot == 0 {
+			ri.main = r
+		}
+	}
+
+	linkStyle, err := p.f.NewStyle(&excelize.Style{Font: &excelize.Font{Color: "0563C1", Underline: "single"}})
+	if err != nil {
+		return fmt.Errorf("failed to create link style: %w", err)
+	}
+	if err := p.writeInfoHeaders(cols); err != nil {
+		return err
+	}
+	for ref, ri := range rows {
+		for _, col := range cols {
+			cell, err := excelize.JoinCellName(col[1], ref.Row)
+			if err != nil {
+				return fmt.Errorf("invalid %s column: %w", col[0], err)
+			}
+			if err := p.writeInfoCell(ref.Sheet, cell, col[0], ri, linkStyle); err != nil {
+				return fmt.Errorf("failed to write %s at %s!%s: %w", col[0], ref.Sheet, cell, err)
+			}
+		}
+	}
+	if p.Info.HighlightMissing {
+		return p.highlightMissing()
+	}
+	return nil
+}
+
+// writeInfoCell writes one info column of a row. Cells of missing rows are
+// cleared so values of an earlier run do not linger.
+func (p *Processor) writeInfoCell(sheet, cell, kind string, ri *rowInfo, linkStyle int) error {
+	r := ri.main
+	var value any
+	switch kind {
+	case "status":
+		value = ri.status
+	case "file":
+		value = r.File
+	case "dims":
+		if r.Width > 0 && r.Height > 0 {
+			value = fmt.Sprintf("%dx%d", r.Width, r.Height)
+		}
+	case "size":
+		if r.Bytes > 0 {
+			value = r.Bytes
+		}
+	case "link":
+		if r.File == "" {
+			if err := p.f.SetCellHyperLink(sheet, cell, "", "None"); err != nil {
+				return err
+			}
+			break
+		}
+		link, err := fileURL(p.imagePath(r.File))
+		if err != nil {
+			return err
+		}
+		if err := p.f.SetCellHyperLink(sheet, cell, link, "External"); err != nil {
+			return err
+		}
+		if err := p.f.SetCellStyle(sheet, cell, cell, linkStyle); err != nil {
+			return err
+		}
+		value = filepath.Base(r.File)
+	}
+	if value == nil {
+		value = ""
+	}
+	return p.f.SetCellValue(sheet, cell, value)
+}
+
+// writeInfoHeaders labels the info columns in the header row of every
+// sheet, leaving existing headers alone
+func (p *Processor) writeInfoHeaders(cols [][2]string) error {
+	for _, stat := range p.SheetStats {
+		info := p.sheets[stat.Name]
+		if info.HeaderRow < 1 {
+			continue
+		}
+		for _, col := range cols {
+			cell, err := excelize.JoinCellName(col[1], info.HeaderRow)
+			if err != nil {
+				return fmt.Errorf("invalid %s column: %w", col[0], err)
+			}
+			if v, _ := p.f.GetCellValue(info.Name, cell); v != "" {
+				continue
+			}
+			if err := p.f.SetCellValue(info.Name, cell, infoHeaders[col[0]]); err != nil {
+				return fmt.Errorf("failed to write %s column header: %w", col[0], err)
+			}
+		}
+	}
+	return nil
+}
+
+// highlightMissing adds a conditional format filling the data rows whose
+// status is InfoMissing red, once per sheet and range
+func (p *Processor) highlightMissing() error {
+	style, err := p.f.NewConditionalStyle(&excelize.Style{
+		Font: &excelize.Font{Color: "9C0006"},
+		Fill: excelize.Fill{Type: "pattern", Color: []string{"FFC7CE"}, Pattern: 1},
+	})
+	if err != nil {
+		return fmt.Errorf("failed to create highlight style: %w", err)
+	}
+
+	// Data rows span from below the header to the last row with a code
+	last := make(map[string]int)
+	for _, refs := range p.productMap {
+		for _, ref := range refs {
+			last[ref.Sheet] = max(last[ref.Sheet], ref.Row)
+		}
+	}
+	statusCol, _ := excelize.ColumnNameToNumber(p.Info.Status)
+	for _, stat := range p.SheetStats {
+		info := p.sheets[stat.Name]
+		if last[info.Name] == 0 {
+			continue
+		}
+		lastCol := max(info.lastCol, statusCol)
+		for _, col := range p.Info.columns() {
+			n, _ := excelize.ColumnNameToNumber(col[1])
+			lastCol = max(lastCol, n)
+		}
+		first := info.HeaderRow + 1
+		from, _ := excelize.CoordinatesToCellName(1, first)
+		to, _ := excelize.CoordinatesToCellName(lastCol, last[info.Name])
+		rangeRef := from + ":" + to
+		criteria := fmt.Sprintf(`$%s%d="%s"`, strings.ToUpper(p.Info.Status), first, InfoMissing)
+
+		existing, err := p.f.GetConditionalFormats(info.Name)
+		if err != nil {
+			return fmt.Errorf("failed to read conditional formats: %w", err)
+		}
+		added := false
+		for _, opt := range existing[rangeRef] {
+			added = added || opt.Type == "formula" && opt.Criteria == criteria
+		}
+		if added {
+			continue // Highlighted by an earlier run
+		}
+		if err := p.f.SetConditionalFormat(info.Name, rangeRef, []excelize.ConditionalFormatOptions{
+			{Type: "formula", Criteria: criteria, Format: &style},
+		}); err != nil {
+			return fmt.Errorf("failed to highlight missing rows of sheet '%s': %w", info.Name, err)
+		}
+	}
+	return nil
+}
+
+// fileURL returns the file:// URL of a local path
+func fileURL(path string) (string, error) {
+	abs, err := filepath.Abs(path)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve image path: %w", err)
+	}
+	slashed := filepath.ToSlash(abs)
+	if !strings.HasPrefix(slashed, "/") {
+		slashed = "/" + slashed // Windows drive letter
+	}
+	return (&url.URL{Scheme: "file", Path: slashed}).String(), nil
+}
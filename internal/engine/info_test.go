@@ -0,0 +1,109 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestInfoColumns_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    InfoColumns
+		wantErr bool
+	}{
+		{"empty", InfoColumns{}, false},
+		{"all", InfoColumns{Status: "E", File: "F", Dims: "G", Size: "H", Link: "I", HighlightMissing: true}, false},
+		{"bad column", InfoColumns{File: "1"}, true},
+		{"highlight without status", InfoColumns{File: "F", HighlightMissing: true}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessor_RunWritesInfoColumns(t *testing.T) {
+	tempDir := t.TempDir()
+	excelPath := filepath.Join(tempDir, "test.xlsx")
+	imageDir := filepath.Join(tempDir, "images")
+	_ = os.Mkdir(imageDir, 0755)
+
+	f := excelize.NewFile()
+	_ = f.SetSheetRow("Sheet1", "A1", &[]string{"SKU", "Photo", "Name", "Status"})
+	_ = f.SetSheetRow("Sheet1", "A2", &[]string{"P001", "", "Found"})
+	_ = f.SetSheetRow("Sheet1", "A3", &[]string{"P002", "", "Missing"})
+	_ = f.SetSheetRow("Sheet1", "A4", &[]string{"P003", "", "Broken"})
+	if err := f.SaveAs(excelPath); err != nil {
+		t.Fatal(err)
+	}
+	_ = createDummyImage(filepath.Join(imageDir, "P001.png"), 120, 80)
+	_ = os.WriteFile(filepath.Join(imageDir, "P003.png"), []byte("not a png"), 0644)
+	stat, _ := os.Stat(filepath.Join(imageDir, "P001.png"))
+
+	run := func(path string) *Processor {
+		t.Helper()
+		p := NewProcessor(path, imageDir, "A", "B", "Sheet1", 2, 100, 20)
+		p.HeaderRow = 1
+		p.Info = InfoColumns{Status: "D", File: "E", Dims: "F", Size: "G", Link: "H", HighlightMissing: true}
+		if err := p.Run(context.Background()); err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+		return p
+	}
+	p := run(excelPath)
+
+	out, err := excelize.OpenFile(p.OutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out.Close()
+	want := [][]string{
+		{"Status", "Image File", "Image Size (px)", "File Size (bytes)", "Image Link"}, // D1 is kept
+		{InfoOK, "P001.png", "120x80", strconv.FormatInt(stat.Size(), 10), "P001.png"},
+		{InfoMissing, "", "", "", ""},
+		{InfoError, "P003.png", "", "", "P003.png"}, // Sizes are unknown when loading fails
+	}
+	for i, row := range want {
+		for j, v := range row {
+			cell, _ := excelize.CoordinatesToCellName(4+j, i+1)
+			if got, _ := out.GetCellValue("Sheet1", cell); got != v {
+				t.Errorf("%s = %q, want %q", cell, got, v)
+			}
+		}
+	}
+	if ok, target, err := out.GetCellHyperLink("Sheet1", "H2"); err != nil || !ok || !strings.HasPrefix(target, "file:///") || !strings.HasSuffix(target, "/images/P001.png") {
+		t.Errorf("H2 links to %q (%v, %v)", target, ok, err)
+	}
+	if ok, _, _ := out.GetCellHyperLink("Sheet1", "H3"); ok {
+		t.Error("H3 of a missing row has a link")
+	}
+
+	formats, err := out.GetConditionalFormats("Sheet1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	rule := formats["A2:H4"]
+	if len(formats) != 1 || len(rule) != 1 || rule[0].Type != "formula" || rule[0].Criteria != `$D2="MISSING"` {
+		t.Errorf("conditional formats = %+v", formats)
+	}
+
+	// A re-run on the output keeps a single highlight rule
+	again := run(p.OutputPath)
+	out2, err := excelize.OpenFile(again.OutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer out2.Close()
+	if formats, _ := out2.GetConditionalFormats("Sheet1"); len(formats["A2:H4"]) != 1 {
+		t.Errorf("re-run conditional formats = %+v", formats)
+	}
+}
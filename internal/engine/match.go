@@ -0,0 +1,201 @@
+package engine
+
+import (
+	"errors"
+	"strings"
+	"unicode"
+
+	"golang.org/x/text/unicode/norm"
+)
+
+// Match rules reported for every matched product code. Normalization steps are
+// cumulative and tried in this order, so the reported rule is the first step
+// that made the code and the filename equal. Codes matched through
+// MatchOptions.Pattern are reported with a "pattern+" prefix.
+const (
+	RuleExact           = "exact"
+	RuleUnicode         = "unicode"
+	RuleIgnoreCase      = "ignore-case"
+	RuleFoldDiacritics  = "fold-diacritics"
+	RuleStripSeparators = "strip-separators"
+	RulePadZeros        = "pad-zeros"
+	RulePattern         = "pattern"
+)
+
+// codePlaceholder marks the position of the product code in MatchOptions.Pattern
+const codePlaceholder = "{code}"
+
+// MatchOptions controls how product codes from the sheet are compared with image filenames
+type MatchOptions struct {
+	IgnoreCase      bool   // "p001" matches "P001.JPG"
+	FoldDiacritics  bool   // "Ảnh-01" matches "Anh-01.jpg"
+	StripSeparators bool   // "P-001" and "P 001" match "P001.jpg"
+	PadZeros        int    // Numeric codes shorter than this are left-padded with zeros ("1234" matches "001234.jpg")
+	Pattern         string // Filename layout around the code, e.g. "IMG_{code}_front"
+}
+
+// Match records which image file was chosen for a product code and why
+type Match struct {
+	Code     string   `json:"code"`
+	File     string   `json:"file"`
+	Variants []string `json:"variants,omitempty"` // Additional images placed after File
+	Rule     string   `json:"rule"`
+	Rows     []RowRef `json:"rows,omitempty"` // Rows the match applies to when not every row of the code, see Processor.PathCol
+}
+
+// normStep is one cumulative normalization applied to both codes and filenames
+type normStep struct {
+	rule string
+	fn   func(string) string
+}
+
+// matcher indexes image filenames and resolves product codes against them
+type matcher struct {
+	opts   MatchOptions
+	prefix string
+	suffix string
+	steps  []normStep
+	// levels[0] holds raw stems, levels[i] the stems after steps[:i].
+	// Each level has one map for plain stems and one for pattern-extracted
+	// codes, both pointing to an image group index.
+	levels [][2]map[string]int
+}
+
+// newMatcher validates the options and prepares an empty index
+func newMatcher(opts MatchOptions) (*matcher, error) {
+	m := &matcher{opts: opts}
+
+	if opts.Pattern != "" {
+		prefix, suffix, ok := strings.Cut(opts.Pattern, codePlaceholder)
+		if !ok {
+			return nil, errors.New("match pattern must contain " + codePlaceholder)
+		}
+		m.prefix, m.suffix = prefix, suffix
+	}
+
+	m.steps = append(m.steps, normStep{RuleUnicode, norm.NFC.String})
+	if opts.IgnoreCase {
+		m.steps = append(m.steps, normStep{RuleIgnoreCase, strings.ToLower})
+	}
+	if opts.FoldDiacritics {
+		m.steps = append(m.steps, normStep{RuleFoldDiacritics, foldDiacritics})
+	}
+	if opts.StripSeparators {
+		m.steps = append(m.steps, normStep{RuleStripSeparators, stripSeparators})
+	}
+	if opts.PadZeros > 0 {
+		width := opts.PadZeros
+		m.steps = append(m.steps, normStep{RulePadZeros, func(s string) string { return padZeros(s, width) }})
+	}
+
+	m.levels = make([][2]map[string]int, len(m.steps)+1)
+	for i := range m.levels {
+		m.levels[i] = [2]map[string]int{{}, {}}
+	}
+	return m, nil
+}
+
+// add indexes an image group under its stem (and the code extracted by the
+// pattern). Keys already taken by an earlier group are kept.
+func (m *matcher) add(stem string, group int) {
+	m.addKey(0, stem, group)
+	if code, ok := m.extract(stem); ok {
+		m.addKey(1, code, group)
+	}
+}
+
+func (m *matcher) addKey(source int, key string, group int) {
+	for i := range m.levels {
+		if i > 0 {
+			key = m.steps[i-1].fn(key)
+		}
+		if _, taken := m.levels[i][source][key]; !taken {
+			m.levels[i][source][key] = group
+		}
+	}
+}
+
+// lookup returns the image group matching code and the rule that produced the match
+func (m *matcher) lookup(code string) (group int, rule string, ok bool) {
+	key := code
+	for i := range m.levels {
+		if i > 0 {
+			key = m.steps[i-1].fn(key)
+		}
+		for source := range 2 {
+			if group, ok = m.levels[i][source][key]; !ok {
+				continue
+			}
+			switch {
+			case i == 0 && source == 0:
+				rule = RuleExact
+			case i == 0:
+				rule = RulePattern
+			case source == 0:
+				rule = m.steps[i-1].rule
+			default:
+				rule = RulePattern + "+" + m.steps[i-1].rule
+			}
+			return group, rule, true
+		}
+	}
+	return 0, "", false
+}
+
+// extract returns the code embedded in stem according to the configured pattern
+func (m *matcher) extract(stem string) (string, bool) {
+	if m.opts.Pattern == "" || len(stem) <= len(m.prefix)+len(m.suffix) {
+		return "", false
+	}
+	head := stem[:len(m.prefix)]
+	tail := stem[len(stem)-len(m.suffix):]
+	if m.opts.IgnoreCase {
+		if !strings.EqualFold(head, m.prefix) || !strings.EqualFold(tail, m.suffix) {
+			return "", false
+		}
+	} else if head != m.prefix || tail != m.suffix {
+		return "", false
+	}
+	return stem[len(m.prefix) : len(stem)-len(m.suffix)], true
+}
+
+// foldDiacritics removes combining marks, e.g. "Ảnh" -> "Anh", and maps the
+// Vietnamese "đ" which has no decomposition
+func foldDiacritics(s string) string {
+	var b strings.Builder
+	for _, r := range norm.NFD.String(s) {
+		switch {
+		case unicode.Is(unicode.Mn, r):
+			continue
+		case r == 'đ':
+			r = 'd'
+		case r == 'Đ':
+			r = 'D'
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// stripSeparators keeps only letters and digits
+func stripSeparators(s string) string {
+	return strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) {
+			return r
+		}
+		return -1
+	}, s)
+}
+
+// padZeros left-pads purely numeric strings with zeros up to width
+func padZeros(s string, width int) string {
+	if s == "" || len(s) >= width {
+		return s
+	}
+	for _, r := range s {
+		if r < '0' || r > '9' {
+			return s
+		}
+	}
+	return strings.Repeat("0", width-len(s)) + s
+}
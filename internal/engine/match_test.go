@@ -0,0 +1,99 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestMatcher_Lookup(t *testing.T) {
+	files := []string{"P001", "ABC-9", "IMG_X77_front", "Ảnh-01", "001234", "Đèn", "\u00e9clair"}
+
+	tests := []struct {
+		name     string
+		opts     MatchOptions
+		code     string
+		wantFile string
+		wantRule string
+	}{
+		{"exact", MatchOptions{}, "P001", "P001", RuleExact},
+		{"case mismatch without option", MatchOptions{}, "p001", "", ""},
+		{"ignore case", MatchOptions{IgnoreCase: true}, "p001", "P001", RuleIgnoreCase},
+		{"nfc", MatchOptions{}, "e\u0301clair", "\u00e9clair", RuleUnicode},
+		{"strip separators", MatchOptions{StripSeparators: true}, "ABC 9", "ABC-9", RuleStripSeparators},
+		{"strip separators on both sides", MatchOptions{StripSeparators: true}, "P.001", "P001", RuleStripSeparators},
+		{"fold diacritics", MatchOptions{FoldDiacritics: true}, "Anh-01", "Ảnh-01", RuleFoldDiacritics},
+		{"fold vietnamese d", MatchOptions{FoldDiacritics: true, IgnoreCase: true}, "den", "Đèn", RuleFoldDiacritics},
+		{"pad zeros", MatchOptions{PadZeros: 6}, "1234", "001234", RulePadZeros},
+		{"pad zeros ignores non numeric", MatchOptions{PadZeros: 6}, "P1", "", ""},
+		{"pattern", MatchOptions{Pattern: "IMG_{code}_front"}, "X77", "IMG_X77_front", RulePattern},
+		{"pattern with ignore case", MatchOptions{Pattern: "img_{code}_FRONT", IgnoreCase: true}, "x77", "IMG_X77_front", RulePattern + "+" + RuleIgnoreCase},
+		{"missing", MatchOptions{IgnoreCase: true, StripSeparators: true}, "P002", "", ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			m, err := newMatcher(tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			for i, stem := range files {
+				m.add(stem, i)
+			}
+			group, rule, ok := m.lookup(tt.code)
+			file := ""
+			if ok {
+				file = files[group]
+			}
+			if ok != (tt.wantFile != "") || file != tt.wantFile || rule != tt.wantRule {
+				t.Errorf("lookup(%q) = %q, %q, %v; want %q, %q", tt.code, file, rule, ok, tt.wantFile, tt.wantRule)
+			}
+		})
+	}
+}
+
+func TestNewMatcher_InvalidPattern(t *testing.T) {
+	if _, err := newMatcher(MatchOptions{Pattern: "IMG_front"}); err == nil {
+		t.Error("expected error for pattern without {code}")
+	}
+}
+
+func TestProcessor_RunNormalizedMatch(t *testing.T) {
+	tempDir := t.TempDir()
+	excelPath := filepath.Join(tempDir, "test.xlsx")
+	imageDir := filepath.Join(tempDir, "images")
+	_ = os.Mkdir(imageDir, 0755)
+
+	if err := createDummyExcel(excelPath, "Sheet1", "A", []string{"p-001", "P002"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = createDummyImage(filepath.Join(imageDir, "P001.PNG"), 10, 10)
+	_ = createDummyImage(filepath.Join(imageDir, "P002.png"), 10, 10)
+
+	p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 2, 100, 20)
+	p.Match = MatchOptions{IgnoreCase: true, StripSeparators: true}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if len(p.MissingCodes) != 0 {
+		t.Errorf("expected no missing codes, got %v", p.MissingCodes)
+	}
+	want := []Match{
+		{Code: "P002", File: "P002.png", Rule: RuleExact},
+		{Code: "p-001", File: "P001.PNG", Rule: RuleStripSeparators},
+	}
+	if len(p.Matches) != len(want) {
+		t.Fatalf("expected %d matches, got %v", len(want), p.Matches)
+	}
+	for i := range want {
+		if !reflect.DeepEqual(p.Matches[i], want[i]) {
+			t.Errorf("match %d = %+v, want %+v", i, p.Matches[i], want[i])
+		}
+	}
+	if p.ProcessedCount != 2 {
+		t.Errorf("expected 2 processed images, got %d", p.ProcessedCount)
+	}
+}
@@ -0,0 +1,51 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// RulePath reports images named by the path column, see Processor.PathCol
+const RulePath = "path"
+
+// resolvePath returns the image files a path column value names, as Job.File
+// values. Relative values resolve against the image directory; globs may
+// match several files, which become variants in name order.
+func (p *Processor) resolvePath(value string) []string {
+	path := filepath.FromSlash(value)
+	if !filepath.IsAbs(path) {
+		path = filepath.Join(p.ImageDir, path)
+	}
+
+	var found []string
+	if strings.ContainsAny(value, "*?[") {
+		matches, err := filepath.Glob(path)
+		if err != nil {
+			return nil // Malformed pattern
+		}
+		for _, m := range matches {
+			if info, err := os.Stat(m); err == nil && info.Mode().IsRegular() && supportedExts[strings.ToLower(filepath.Ext(m))] {
+				found = append(found, m)
+			}
+		}
+	} else if info, err := os.Stat(path); err == nil && info.Mode().IsRegular() {
+		found = append(found, path)
+	}
+
+	// Files inside the image directory are reported relative to it like matched ones
+	for i, f := range found {
+		if rel, err := filepath.Rel(p.ImageDir, f); err == nil && filepath.IsLocal(rel) {
+			found[i] = rel
+		}
+	}
+	return found
+}
+
+// imagePath returns the path of a Job.File value
+func (p *Processor) imagePath(file string) string {
+	if filepath.IsAbs(file) {
+		return file
+	}
+	return filepath.Join(p.ImageDir, file)
+}
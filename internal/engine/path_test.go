@@ -0,0 +1,129 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestProcessor_ResolvePath(t *testing.T) {
+	tempDir := t.TempDir()
+	imageDir := filepath.Join(tempDir, "images")
+	_ = os.MkdirAll(filepath.Join(imageDir, "spring"), 0755)
+	for _, name := range []string{"spring/P001-front.png", "spring/P001-back.png", "spring/notes.txt", "P002.png"} {
+		_ = os.WriteFile(filepath.Join(imageDir, name), []byte("x"), 0644)
+	}
+	shared := filepath.Join(tempDir, "share", "P003.jpg")
+	_ = os.MkdirAll(filepath.Dir(shared), 0755)
+	_ = os.WriteFile(shared, []byte("x"), 0644)
+
+	tests := []struct {
+		name  string
+		value string
+		want  []string
+	}{
+		{"relative", "spring/P001-front.png", []string{filepath.Join("spring", "P001-front.png")}},
+		{"absolute inside", filepath.Join(imageDir, "P002.png"), []string{"P002.png"}},
+		{"absolute outside", shared, []string{shared}},
+		{"glob", "spring/P001-*", []string{filepath.Join("spring", "P001-back.png"), filepath.Join("spring", "P001-front.png")}},
+		{"glob outside", filepath.Join(tempDir, "share", "*"), []string{shared}},
+		{"not found", "spring/P009.png", nil},
+		{"directory", "spring", nil},
+		{"bad pattern", "spring/[", nil},
+	}
+	p := NewProcessor("", imageDir, "A", "B", "Sheet1", 1, 100, 20)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.resolvePath(tt.value); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("resolvePath(%q) = %v, want %v", tt.value, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessor_RunPathColumn(t *testing.T) {
+	tempDir := t.TempDir()
+	excelPath := filepath.Join(tempDir, "test.xlsx")
+	imageDir := filepath.Join(tempDir, "images")
+	_ = os.MkdirAll(filepath.Join(imageDir, "spring"), 0755)
+	shared := filepath.Join(tempDir, "share", "photo.png")
+	_ = os.MkdirAll(filepath.Dir(shared), 0755)
+
+	f := excelize.NewFile()
+	rows := [][]string{
+		{"P001", "", "", "spring/P001-front.png"},
+		{"P002", "", "", shared},
+		{"P003", "", "", "spring/P003-*.png"},
+		{"P004"},
+		{"P005", "", "", "spring/P005.png"},
+		{"P001"},
+	}
+	for i, row := range rows {
+		cell, _ := excelize.CoordinatesToCellName(1, i+1)
+		_ = f.SetSheetRow("Sheet1", cell, &row)
+	}
+	if err := f.SaveAs(excelPath); err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"spring/P001-front.png", "spring/P003-a.png", "spring/P003-b.png", "P001.png", "P004.png", "P005.png"} {
+		_ = createDummyImage(filepath.Join(imageDir, name), 50, 50)
+	}
+	_ = createDummyImage(shared, 50, 50)
+
+	p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 2, 100, 20)
+	p.PathCol = "D"
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	type placed struct {
+		Row    int
+		File   string
+		Slot   int
+		Status string
+	}
+	want := []placed{
+		{1, filepath.Join("spring", "P001-front.png"), 0, StatusInserted},
+		{2, shared, 0, StatusInserted},
+		{3, filepath.Join("spring", "P003-a.png"), 0, StatusInserted},
+		{3, filepath.Join("spring", "P003-b.png"), 1, StatusInserted},
+		{4, "P004.png", 0, StatusInserted},
+		{5, "P005.png", 0, StatusInserted}, // The path finds no file, the code does
+		{6, "P001.png", 0, StatusInserted}, // No path, matched by code
+	}
+	var got []placed
+	for _, row := range p.Report.Rows {
+		got = append(got, placed{row.Row, row.File, row.Slot, row.Status})
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("report rows = %v\nwant %v", got, want)
+	}
+
+	rules := make(map[string]int)
+	for _, m := range p.Matches {
+		rules[m.Rule]++
+	}
+	if rules[RulePath] != 3 || rules[RuleExact] != 3 {
+		t.Errorf("match rules = %v, want 3 path and 3 exact", rules)
+	}
+
+	pp := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 1, 100, 20)
+	pp.PathCol = "D"
+	preview, err := pp.Preview(context.Background())
+	if err != nil {
+		t.Fatalf("Preview() error: %v", err)
+	}
+	for _, row := range preview.Rows {
+		if row.File == "" || slices.Contains(row.Problems, ProblemMissing) {
+			t.Errorf("preview row %d: file %q, problems %v", row.Row, row.File, row.Problems)
+		}
+	}
+	if preview.Rows[0].File != filepath.Join("spring", "P001-front.png") || preview.Rows[5].File != "P001.png" {
+		t.Errorf("preview files = %q and %q", preview.Rows[0].File, preview.Rows[5].File)
+	}
+}
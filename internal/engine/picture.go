@@ -0,0 +1,194 @@
+package engine
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// LinkFile makes pictures link to their original image file, see PictureOptions.Link
+const LinkFile = "file"
+
+// PictureOptions describes the pictures for the selection pane, screen
+// readers and protected sheets. Templates may use these placeholders:
+//
+//	{code}   product code
+//	{file}   image file relative to the image directory
+//	{sheet}  sheet name
+//	{row}    row number
+//	{col:X}  value of column X on the row
+type PictureOptions struct {
+	Name            string // Picture name template, e.g. "{code}" (default "Picture N")
+	AltText         string // Alt text template, e.g. "{col:C} ({code})"
+	Link            string // LinkFile, or a URL template such as "https://shop.example/p/{code}"
+	LockAspectRatio bool   // Keep the aspect ratio when the picture is resized in Excel
+	Unlocked        bool   // Allow changing the picture when the sheet is protected
+	NoPrint         bool   // Leave the pictures out of printouts
+}
+
+var (
+	// templatePlaceholder matches anything in braces, templateField the known placeholders
+	templatePlaceholder = regexp.MustCompile(`\{[^{}]*\}`)
+	templateField       = regexp.MustCompile(`^\{(code|file|sheet|row|col:[A-Za-z]{1,3})\}$`)
+
+	// nameMarker matches a picture name followed by the marker that
+	// describePicture puts in front of the alt text
+	nameMarker = regexp.MustCompile(`name="[^"]*" descr="\x{E000}(\d+)\x{E001}`)
+)
+
+// validate checks the picture templates
+func (o PictureOptions) validate() error {
+	for _, tmpl := range []string{o.Name, o.AltText, o.Link} {
+		for _, ph := range templatePlaceholder.FindAllString(tmpl, -1) {
+			if !templateField.MatchString(ph) {
+				return fmt.Errorf("unknown placeholder %s in '%s'", ph, tmpl)
+			}
+		}
+	}
+	return nil
+}
+
+// expandTemplate replaces the placeholders of tmpl for the picture of res
+// on ref, passing every value through escape. The segments of {file} are
+// escaped one by one so URL templates keep its folders.
+func (p *Processor) expandTemplate(tmpl string, res Result, ref RowRef, escape func(string) string) string {
+	return templatePlaceholder.ReplaceAllStringFunc(tmpl, func(ph string) string {
+		field := ph[1 : len(ph)-1]
+		var v string
+		switch {
+		case field == "code":
+			v = res.Job.ProductCode
+		case field == "file":
+			parts := strings.Split(filepath.ToSlash(res.Job.File), "/")
+			for i, part := range parts {
+				parts[i] = escape(part)
+			}
+			return strings.Join(parts, "/")
+		case field == "sheet":
+			v = ref.Sheet
+		case field == "row":
+			v = strconv.Itoa(ref.Row)
+		case strings.HasPrefix(field, "col:"):
+			v, _ = p.f.GetCellValue(ref.Sheet, strings.ToUpper(field[4:])+strconv.Itoa(ref.Row))
+		default:
+			return ph
+		}
+		return escape(v)
+	})
+}
+
+// describePicture sets the alt text, hyperlink and flags of a picture. A
+// picture name is remembered for renamePictures and marked in the alt text.
+func (p *Processor) describePicture(format *excelize.GraphicOptions, res Result, ref RowRef) error {
+	o := p.Pictures
+	keep := func(s string) string { return s }
+	locked, printed := !o.Unlocked, !o.NoPrint
+	format.LockAspectRatio = o.LockAspectRatio
+	format.Locked = &locked
+	format.PrintObject = &printed
+	format.AltText = p.expandTemplate(o.AltText, res, ref, keep)
+
+	switch o.Link {
+	case "":
+	case LinkFile:
+		link, err := fileURL(res.Job.ImagePath)
+		if err != nil {
+			return err
+		}
+		format.Hyperlink = link
+		format.HyperlinkType = "External"
+	default:
+		format.Hyperlink = p.expandTemplate(o.Link, res, ref, url.PathEscape)
+		format.HyperlinkType = "External"
+	}
+
+	if o.Name != "" {
+		p.pictureNames = append(p.pictureNames, p.expandTemplate(o.Name, res, ref, keep))
+		format.AltText = fmt.Sprintf("\uE000%d\uE001%s", len(p.pictureNames)-1, format.AltText)
+	}
+	return nil
+}
+
+// renamePictures gives the pictures of the workbook at path their names.
+// excelize always names pictures "Picture N", so the drawing parts are
+// rewritten, replacing the marked names and removing the markers.
+func renamePictures(path string, names []string) error {
+	isDrawing := func(name string) bool {
+		return strings.HasPrefix(name, "xl/drawings/drawing") && strings.HasSuffix(name, ".xml")
+	}
+	return rewriteParts(path, isDrawing, func(_ string, data []byte) []byte {
+		return nameMarker.ReplaceAllFunc(data, func(m []byte) []byte {
+			i, _ := strconv.Atoi(string(nameMarker.FindSubmatch(m)[1]))
+			if i >= len(names) {
+				return m
+			}
+			var name bytes.Buffer
+			_ = xml.EscapeText(&name, []byte(names[i]))
+			return []byte(`name="` + name.String() + `" descr="`)
+		})
+	})
+}
+
+// rewriteParts rewrites the parts of the workbook at path that match. edit
+// returns the new content of a part, or nil to remove it; other parts are
+// copied unchanged.
+func rewriteParts(path string, match func(name string) bool, edit func(name string, data []byte) []byte) error {
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		return err
+	}
+	defer zr.Close()
+
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+"-*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	zw := zip.NewWriter(tmp)
+	for _, f := range zr.File {
+		if !match(f.Name) {
+			if err := zw.Copy(f); err != nil {
+				return err
+			}
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		data, err := io.ReadAll(rc)
+		rc.Close()
+		if err != nil {
+			return err
+		}
+		if data = edit(f.Name, data); data == nil {
+			continue
+		}
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: f.Name, Method: zip.Deflate, Modified: f.Modified})
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := zw.Close(); err != nil {
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	zr.Close() // Windows cannot replace an open file
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,151 @@
+package engine
+
+import (
+	"context"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestPictureOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    PictureOptions
+		wantErr bool
+	}{
+		{"empty", PictureOptions{}, false},
+		{"known placeholders", PictureOptions{Name: "{code}", AltText: "{col:C} {file} {sheet}!{row}", Link: "https://x/{code}"}, false},
+		{"link to file", PictureOptions{Link: LinkFile}, false},
+		{"unknown placeholder", PictureOptions{AltText: "{price}"}, true},
+		{"bad column", PictureOptions{Name: "{col:}"}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessor_ExpandTemplate(t *testing.T) {
+	keep := func(s string) string { return s }
+	tests := []struct {
+		name   string
+		tmpl   string
+		code   string
+		file   string
+		escape func(string) string
+		want   string
+	}{
+		{"plain", "{code} {file}", "P001", filepath.Join("spring", "P001 front.png"), keep, "P001 spring/P001 front.png"},
+		{"url keeps folders", "https://cdn.example/{file}", "P001", filepath.Join("spring", "P001 front.png"), url.PathEscape, "https://cdn.example/spring/P001%20front.png"},
+		{"url escapes codes", "https://shop.example/p/{code}", "A/B 1", "A.png", url.PathEscape, "https://shop.example/p/A%2FB%201"},
+		{"sheet and row", "{sheet}!{row}", "P001", "P001.png", keep, "Sheet1!7"},
+	}
+	p := NewProcessor("", "", "A", "B", "Sheet1", 1, 100, 20)
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			res := Result{Job: Job{ProductCode: tt.code, File: tt.file}}
+			if got := p.expandTemplate(tt.tmpl, res, RowRef{Sheet: "Sheet1", Row: 7}, tt.escape); got != tt.want {
+				t.Errorf("expandTemplate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestProcessor_RunDescribesPictures(t *testing.T) {
+	tests := []struct {
+		name      string
+		opts      PictureOptions
+		want      []string // Expected in the drawing part
+		wantLinks []string // Expected in the drawing relationships
+	}{
+		{
+			name: "defaults",
+			opts: PictureOptions{},
+			want: []string{`name="Picture 2" descr=""`, `fLocksWithSheet="true"`, `fPrintsWithSheet="true"`},
+		},
+		{
+			name: "templates and flags",
+			opts: PictureOptions{
+				Name:            "{code} {col:C}",
+				AltText:         "{col:c} ({code}, {sheet}!{row})",
+				Link:            "https://shop.example/p/{col:C}",
+				LockAspectRatio: true,
+				Unlocked:        true,
+				NoPrint:         true,
+			},
+			want: []string{
+				`name="P001 Red &amp; Blue" descr="Red &amp; Blue (P001, Sheet1!1)"`,
+				`name="P002 Green" descr="Green (P002, Sheet1!2)"`,
+				`noChangeAspect="true"`,
+				`fLocksWithSheet="false"`,
+				`fPrintsWithSheet="false"`,
+			},
+			wantLinks: []string{`Target="https://shop.example/p/Red%20&amp;%20Blue"`, `Target="https://shop.example/p/Green"`, `TargetMode="External"`},
+		},
+		{
+			name:      "link to file",
+			opts:      PictureOptions{Link: LinkFile},
+			wantLinks: []string{`Target="file:///`, `/images/P001.png"`, `TargetMode="External"`},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			excelPath := filepath.Join(tempDir, "test.xlsx")
+			imageDir := filepath.Join(tempDir, "images")
+			_ = os.Mkdir(imageDir, 0755)
+
+			f := excelize.NewFile()
+			_ = f.SetSheetRow("Sheet1", "A1", &[]string{"P001", "", "Red & Blue"})
+			_ = f.SetSheetRow("Sheet1", "A2", &[]string{"P002", "", "Green"})
+			if err := f.SaveAs(excelPath); err != nil {
+				t.Fatal(err)
+			}
+			_ = createDummyImage(filepath.Join(imageDir, "P001.png"), 100, 100)
+			_ = createDummyImage(filepath.Join(imageDir, "P002.png"), 50, 100)
+
+			p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 1, 100, 20)
+			p.Pictures = tt.opts
+			if err := p.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+			if p.ProcessedCount != 2 {
+				t.Fatalf("processed %d pictures, want 2", p.ProcessedCount)
+			}
+
+			drawing := readDrawing(t, p.OutputPath)
+			if strings.ContainsAny(drawing, "\uE000\uE001") {
+				t.Error("drawing still contains name markers")
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(drawing, want) {
+					t.Errorf("drawing lacks %s:\n%s", want, drawing)
+				}
+			}
+			rels := readPart(t, p.OutputPath, "xl/drawings/_rels/drawing1.xml.rels")
+			for _, want := range tt.wantLinks {
+				if !strings.Contains(rels, want) {
+					t.Errorf("drawing relationships lack %s:\n%s", want, rels)
+				}
+			}
+
+			// The rewritten workbook still opens
+			out, err := excelize.OpenFile(p.OutputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			if pics, err := out.GetPictures("Sheet1", "B1"); err != nil || len(pics) != 1 {
+				t.Errorf("GetPictures() = %d pictures, %v", len(pics), err)
+			}
+		})
+	}
+}
@@ -0,0 +1,154 @@
+package engine
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Picture alignments for PlacementOptions.Align
+const (
+	AlignCenter       = "center"        // Centred in the cell (default)
+	AlignTopLeft      = "top-left"      // Against the top and left margins
+	AlignBottomCenter = "bottom-center" // Centred horizontally, against the bottom margin
+)
+
+// Anchoring modes for PlacementOptions.Positioning
+const (
+	PositionOneCell  = "oneCell"  // Move with the cell but keep the picture size (default)
+	PositionTwoCell  = "twoCell"  // Move and resize with the cell; sorting and filtering keep pictures with their rows
+	PositionAbsolute = "absolute" // Neither move nor resize with cells
+)
+
+// maxColWidth is Excel's maximum column width in characters
+const maxColWidth = 255
+
+// DefaultMargin is the gap in pixels between a picture and its cell border
+const DefaultMargin = 5
+
+// defaultFontSize is assumed when the workbook does not set one
+const defaultFontSize = 11
+
+// defaultDigitWidth is the maximum digit width of Calibri 11, the default font
+const defaultDigitWidth = 7
+
+// digitWidths holds the advance width of the digits of common fonts in ems.
+// Excel measures column widths in these digits; unknown fonts use Calibri.
+var digitWidths = map[string]float64{
+	"aptos":           0.5,
+	"aptos narrow":    0.48,
+	"arial":           0.556,
+	"arial narrow":    0.456,
+	"calibri":         0.507,
+	"calibri light":   0.507,
+	"cambria":         0.553,
+	"consolas":        0.55,
+	"courier new":     0.6,
+	"georgia":         0.614,
+	"helvetica":       0.556,
+	"segoe ui":        0.559,
+	"tahoma":          0.546,
+	"times new roman": 0.5,
+	"verdana":         0.636,
+}
+
+// PlacementOptions controls where pictures sit inside their cells
+type PlacementOptions struct {
+	Align       string // AlignCenter, AlignTopLeft or AlignBottomCenter
+	MarginX     *int   // Left and right gap in pixels (nil for DefaultMargin)
+	MarginY     *int   // Top and bottom gap in pixels (nil for DefaultMargin)
+	Positioning string // PositionOneCell, PositionTwoCell or PositionAbsolute
+}
+
+// validate checks the placement options
+func (o PlacementOptions) validate() error {
+	switch o.Align {
+	case "", AlignCenter, AlignTopLeft, AlignBottomCenter:
+	default:
+		return errors.New("invalid alignment '" + o.Align + "'")
+	}
+	switch o.Positioning {
+	case "", PositionOneCell, PositionTwoCell, PositionAbsolute:
+	default:
+		return errors.New("invalid positioning '" + o.Positioning + "'")
+	}
+	if x, y := o.margins(); x < 0 || y < 0 {
+		return fmt.Errorf("margins must not be negative, got %d and %d", x, y)
+	}
+	return nil
+}
+
+// positioning returns the anchoring mode, PositionOneCell by default
+func (o PlacementOptions) positioning() string {
+	if o.Positioning == "" {
+		return PositionOneCell
+	}
+	return o.Positioning
+}
+
+// margins returns the horizontal and vertical margins in pixels
+func (o PlacementOptions) margins() (int, int) {
+	margin := func(v *int) int {
+		if v == nil {
+			return DefaultMargin
+		}
+		return *v
+	}
+	return margin(o.MarginX), margin(o.MarginY)
+}
+
+// align returns the offset of a w x h picture inside a box at x, y of
+// boxW x boxH pixels
+func (o PlacementOptions) align(x, y int, boxW, boxH float64, w, h int) (int, int) {
+	dx, dy := (boxW-float64(w))/2, (boxH-float64(h))/2
+	switch o.Align {
+	case AlignTopLeft:
+		dx, dy = 0, 0
+	case AlignBottomCenter:
+		dy = boxH - float64(h)
+	}
+	return x + max(0, int(dx)), y + max(0, int(dy))
+}
+
+// maxDigitWidth returns the pixel width of the widest digit in the default
+// font of f, the unit of Excel column widths
+func maxDigitWidth(f *excelize.File) float64 {
+	family, size := "", 0.0
+	if style, err := f.GetStyle(0); err == nil && style.Font != nil {
+		family, size = style.Font.Family, style.Font.Size
+	}
+	if family == "" {
+		family, _ = f.GetDefaultFont()
+	}
+	if size <= 0 {
+		size = defaultFontSize
+	}
+	em, ok := digitWidths[strings.ToLower(strings.TrimSpace(family))]
+	if !ok {
+		em = digitWidths["calibri"]
+	}
+	return max(1, math.Round(size*96/72*em))
+}
+
+// cellWidth returns the width of the image column in pixels
+func (p *Processor) cellWidth() float64 {
+	digitWidth := p.digitWidth
+	if digitWidth <= 0 {
+		digitWidth = defaultDigitWidth
+	}
+	return colWidthPixels(p.ColWidth, digitWidth)
+}
+
+// colWidthPixels converts a column width in characters to pixels the way
+// Excel does for a font with the given maximum digit width
+func colWidthPixels(width, digitWidth float64) float64 {
+	return math.Trunc((256*width + math.Trunc(128/digitWidth)) / 256 * digitWidth)
+}
+
+// rowHeightPixels converts a row height in points to pixels at 96 DPI
+func rowHeightPixels(height float64) float64 {
+	return math.Round(height * 96 / 72)
+}
@@ -0,0 +1,250 @@
+package engine
+
+import (
+	"archive/zip"
+	"context"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// readDrawing returns the first drawing part of the workbook at path
+func readDrawing(t *testing.T, path string) string {
+	t.Helper()
+	return readPart(t, path, "xl/drawings/drawing1.xml")
+}
+
+// readPart returns a part of the workbook at path
+func readPart(t *testing.T, path, name string) string {
+	t.Helper()
+	zr, err := zip.OpenReader(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer zr.Close()
+	for _, f := range zr.File {
+		if f.Name != name {
+			continue
+		}
+		rc, err := f.Open()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer rc.Close()
+		data, err := io.ReadAll(rc)
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(data)
+	}
+	t.Fatalf("workbook has no %s", name)
+	return ""
+}
+
+func TestColWidthPixels(t *testing.T) {
+	tests := []struct {
+		width, digitWidth, want float64
+	}{
+		{9.140625, 7, 64}, // Excel default column, Calibri 11
+		{20, 7, 140},
+		{20, 8, 160},
+		{10, 6, 60},
+	}
+	for _, tt := range tests {
+		if got := colWidthPixels(tt.width, tt.digitWidth); got != tt.want {
+			t.Errorf("colWidthPixels(%g, %g) = %g, want %g", tt.width, tt.digitWidth, got, tt.want)
+		}
+	}
+	if got := rowHeightPixels(15); got != 20 {
+		t.Errorf("rowHeightPixels(15) = %g, want 20", got)
+	}
+}
+
+func TestMaxDigitWidth(t *testing.T) {
+	tests := []struct {
+		font string
+		want float64
+	}{
+		{"", 7}, // Calibri 11
+		{"Arial", 8},
+		{"Verdana", 9},
+		{"Unknown Sans", 7},
+	}
+	for _, tt := range tests {
+		t.Run(tt.font, func(t *testing.T) {
+			f := excelize.NewFile()
+			defer f.Close()
+			if tt.font != "" {
+				if err := f.SetDefaultFont(tt.font); err != nil {
+					t.Fatal(err)
+				}
+			}
+			if got := maxDigitWidth(f); got != tt.want {
+				t.Errorf("maxDigitWidth() = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestPlacementOptions_Align(t *testing.T) {
+	tests := []struct {
+		align        string
+		w, h         int
+		wantX, wantY int
+	}{
+		{"", 50, 20, 30, 45},
+		{AlignCenter, 50, 20, 30, 45},
+		{AlignTopLeft, 50, 20, 5, 5},
+		{AlignBottomCenter, 50, 20, 30, 85},
+		{AlignCenter, 200, 200, 5, 5}, // Larger than the box
+	}
+	for _, tt := range tests {
+		x, y := PlacementOptions{Align: tt.align}.align(5, 5, 100, 100, tt.w, tt.h)
+		if x != tt.wantX || y != tt.wantY {
+			t.Errorf("align(%q, %dx%d) = %d,%d, want %d,%d", tt.align, tt.w, tt.h, x, y, tt.wantX, tt.wantY)
+		}
+	}
+	if err := (PlacementOptions{Align: "middle"}).validate(); err == nil {
+		t.Error("expected an error for an unknown alignment")
+	}
+	if err := (PlacementOptions{MarginY: ptr(-1)}).validate(); err == nil {
+		t.Error("expected an error for a negative margin")
+	}
+}
+
+func TestPlacementOptions_Margins(t *testing.T) {
+	tests := []struct {
+		name         string
+		opts         PlacementOptions
+		wantX, wantY int
+	}{
+		{"unset", PlacementOptions{}, DefaultMargin, DefaultMargin},
+		{"zero", PlacementOptions{MarginX: ptr(0), MarginY: ptr(0)}, 0, 0},
+		{"custom", PlacementOptions{MarginX: ptr(2), MarginY: ptr(8)}, 2, 8},
+	}
+	for _, tt := range tests {
+		if x, y := tt.opts.margins(); x != tt.wantX || y != tt.wantY {
+			t.Errorf("%s: margins() = %d,%d, want %d,%d", tt.name, x, y, tt.wantX, tt.wantY)
+		}
+	}
+}
+
+// ptr returns a pointer to v, for optional settings
+func ptr[T any](v T) *T {
+	return &v
+}
+
+func TestProcessor_RunAlignsPictures(t *testing.T) {
+	// Row 100pt = 133px, column 20 = 140px with Calibri 11. The 3:1 picture
+	// fills the width of the box and is 43px (or 47px without margins) tall.
+	tests := []struct {
+		name         string
+		placement    PlacementOptions
+		wantX, wantY int
+	}{
+		{"center", PlacementOptions{}, 5, 45},
+		{"top-left", PlacementOptions{Align: AlignTopLeft}, 5, 5},
+		{"bottom-center", PlacementOptions{Align: AlignBottomCenter}, 5, 85},
+		{"margins", PlacementOptions{MarginX: ptr(0), MarginY: ptr(10)}, 0, 43},
+	}
+	offset := regexp.MustCompile(`<xdr:colOff>(\d+)</xdr:colOff><xdr:row>\d+</xdr:row><xdr:rowOff>(\d+)</xdr:rowOff>`)
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			excelPath := filepath.Join(tempDir, "test.xlsx")
+			imageDir := filepath.Join(tempDir, "images")
+			_ = os.Mkdir(imageDir, 0755)
+			if err := createDummyExcel(excelPath, "Sheet1", "A", []string{"P001"}); err != nil {
+				t.Fatal(err)
+			}
+			_ = createDummyImage(filepath.Join(imageDir, "P001.png"), 300, 100)
+
+			p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 1, 100, 20)
+			p.Placement = tt.placement
+			if err := p.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+
+			m := offset.FindStringSubmatch(readDrawing(t, p.OutputPath))
+			if m == nil {
+				t.Fatal("picture anchor not found")
+			}
+			x, _ := strconv.Atoi(m[1])
+			y, _ := strconv.Atoi(m[2])
+			if x/excelize.EMU != tt.wantX || y/excelize.EMU != tt.wantY {
+				t.Errorf("offset = %d,%d px, want %d,%d", x/excelize.EMU, y/excelize.EMU, tt.wantX, tt.wantY)
+			}
+		})
+	}
+}
+
+func TestProcessor_RunPositioning(t *testing.T) {
+	// Row 100pt = 133px, column 20 = 140px. The 1:3 picture is 41x123px,
+	// centred at 49,5, so its two-cell anchor ends at 90,128 in the same cell.
+	tests := []struct {
+		positioning string
+		wantAnchor  string
+		wantTo      bool
+	}{
+		{"", `<xdr:oneCellAnchor>`, false},
+		{PositionOneCell, `<xdr:oneCellAnchor>`, false},
+		{PositionTwoCell, `<xdr:twoCellAnchor editAs="twoCell">`, true},
+		{PositionAbsolute, `<xdr:twoCellAnchor editAs="absolute">`, true},
+	}
+	to := regexp.MustCompile(`<xdr:to><xdr:col>(\d+)</xdr:col><xdr:colOff>(\d+)</xdr:colOff><xdr:row>(\d+)</xdr:row><xdr:rowOff>(\d+)</xdr:rowOff></xdr:to>`)
+
+	for _, tt := range tests {
+		t.Run(tt.positioning, func(t *testing.T) {
+			tempDir := t.TempDir()
+			excelPath := filepath.Join(tempDir, "test.xlsx")
+			imageDir := filepath.Join(tempDir, "images")
+			_ = os.Mkdir(imageDir, 0755)
+			if err := createDummyExcel(excelPath, "Sheet1", "A", []string{"P001"}); err != nil {
+				t.Fatal(err)
+			}
+			_ = createDummyImage(filepath.Join(imageDir, "P001.png"), 100, 300)
+
+			p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 1, 100, 20)
+			p.Placement.Positioning = tt.positioning
+			if err := p.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+
+			drawing := readDrawing(t, p.OutputPath)
+			if !strings.Contains(drawing, tt.wantAnchor) {
+				t.Fatalf("drawing has no %s anchor:\n%s", tt.wantAnchor, drawing)
+			}
+			m := to.FindStringSubmatch(drawing)
+			if (m != nil) != tt.wantTo {
+				t.Fatalf("end anchor found = %v, want %v", m != nil, tt.wantTo)
+			}
+			if m == nil {
+				return
+			}
+			want := []string{"1", strconv.Itoa(90 * excelize.EMU), "0", strconv.Itoa(128 * excelize.EMU)}
+			if got := m[1:]; !reflect.DeepEqual(got, want) {
+				t.Errorf("end anchor col, colOff, row, rowOff = %v, want %v", got, want)
+			}
+
+			f, err := excelize.OpenFile(p.OutputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if h, _ := f.GetRowHeight("Sheet1", 1); h != 100 {
+				t.Errorf("row height = %g, want 100", h)
+			}
+			if w, _ := f.GetColWidth("Sheet1", "B"); w != 20 {
+				t.Errorf("column width = %g, want 20", w)
+			}
+		})
+	}
+}
@@ -0,0 +1,191 @@
+package engine
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"sort"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Problems reported by Preview for a row
+const (
+	ProblemMissing     = "missing"     // No image matches the code
+	ProblemUndecodable = "undecodable" // An image of the code cannot be decoded
+	ProblemOversized   = "oversized"   // An image of the code exceeds MaxPixels and will be rejected
+	ProblemDuplicate   = "duplicate"   // The code also appears on other rows of the sheet
+)
+
+// DefaultMaxPixels is the pixel count above which an image is rejected as a
+// likely decompression bomb, and reported as oversized by Preview
+const DefaultMaxPixels = 100_000_000
+
+// PreviewRow describes what Run would place on one data row
+type PreviewRow struct {
+	Sheet    string   `json:"sheet"`
+	Row      int      `json:"row"`
+	Code     string   `json:"code"`
+	File     string   `json:"file,omitempty"`
+	Variants []string `json:"variants,omitempty"`
+	Rule     string   `json:"rule,omitempty"`
+	Width    int      `json:"width"`  // Pixel size of File
+	Height   int      `json:"height"` // Pixel size of File
+	Problems []string `json:"problems,omitempty"`
+}
+
+// Preview is the outcome of a dry run
+type Preview struct {
+	Rows    []PreviewRow `json:"rows"`
+	Orphans []string     `json:"orphans"` // Images no product code matched
+}
+
+// previewImage holds the header information of one matched image
+type previewImage struct {
+	width, height int
+	problem       string
+}
+
+// Preview maps the sheets and matches the images exactly like Run, but only
+// reads image headers and never modifies or saves the workbook. The result
+// fields (MissingCodes, Matches, Duplicates, ...) are filled as by Run.
+func (p *Processor) Preview(ctx context.Context) (*Preview, error) {
+	if err := p.validate(); err != nil {
+		return nil, err
+	}
+	var err error
+	p.f, err = excelize.OpenFile(p.ExcelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open excel: %w", err)
+	}
+	defer p.f.Close()
+
+	if err := p.mapSheets(ctx); err != nil {
+		return nil, err
+	}
+	idx, err := p.indexImages()
+	if err != nil {
+		return nil, err
+	}
+	p.matchJobs(idx)
+
+	maxPixels := p.maxPixels()
+	images := make(map[string]previewImage)
+	inspect := func(rel string) previewImage {
+		if img, ok := images[rel]; ok {
+			return img
+		}
+		var img previewImage
+		cfg, err := decodeConfigFile(p.imagePath(rel))
+		switch {
+		case err != nil:
+			img.problem = ProblemUndecodable
+		case cfg.Width*cfg.Height > maxPixels:
+			img.problem = ProblemOversized
+		}
+		img.width, img.height = cfg.Width, cfg.Height
+		images[rel] = img
+		return img
+	}
+
+	// Matches limited to some rows (see Processor.PathCol) take precedence
+	matches := make(map[string]Match, len(p.Matches))
+	rowMatches := make(map[RowRef]Match)
+	for _, m := range p.Matches {
+		if m.Rows == nil {
+			matches[m.Code] = m
+		}
+		for _, ref := range m.Rows {
+			rowMatches[ref] = m
+		}
+	}
+	duplicates := make(map[RowRef]bool)
+	for _, d := range p.Duplicates {
+		for _, row := range d.Rows {
+			duplicates[RowRef{Sheet: d.Sheet, Row: row}] = true
+		}
+	}
+
+	preview := &Preview{}
+	for code, refs := range p.productMap {
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		for _, ref := range refs {
+			m, matched := rowMatches[ref]
+			if !matched {
+				m, matched = matches[code]
+			}
+			var problems []string
+			var first previewImage
+			if matched {
+				first = inspect(m.File)
+				for _, rel := range append([]string{m.File}, m.Variants...) {
+					if problem := inspect(rel).problem; problem != "" && !slices.Contains(problems, problem) {
+						problems = append(problems, problem)
+					}
+				}
+			} else {
+				problems = append(problems, ProblemMissing)
+			}
+
+			row := PreviewRow{
+				Sheet:    ref.Sheet,
+				Row:      ref.Row,
+				Code:     code,
+				File:     m.File,
+				Variants: m.Variants,
+				Rule:     m.Rule,
+				Width:    first.width,
+				Height:   first.height,
+				Problems: problems,
+			}
+			if duplicates[ref] {
+				row.Problems = append(append([]string{}, problems...), ProblemDuplicate)
+			}
+			preview.Rows = append(preview.Rows, row)
+		}
+	}
+
+	// Rows follow the sheet order, then the row number
+	sheetOrder := make(map[string]int, len(p.SheetStats))
+	for i, s := range p.SheetStats {
+		sheetOrder[s.Name] = i
+	}
+	sort.Slice(preview.Rows, func(i, j int) bool {
+		a, b := preview.Rows[i], preview.Rows[j]
+		if a.Sheet != b.Sheet {
+			return sheetOrder[a.Sheet] < sheetOrder[b.Sheet]
+		}
+		return a.Row < b.Row
+	})
+
+	preview.Orphans = p.orphanImages(idx)
+	return preview, nil
+}
+
+// orphanImages lists the indexed images that no product code matched, sorted
+func (p *Processor) orphanImages(idx *imageIndex) []string {
+	used := make(map[string]bool)
+	for _, m := range p.Matches {
+		used[m.File] = true
+		for _, v := range m.Variants {
+			used[v] = true
+		}
+	}
+
+	var orphans []string
+	for _, group := range idx.groups {
+		for _, f := range group {
+			if !used[f.Rel] {
+				used[f.Rel] = true // Variant files are indexed twice
+				orphans = append(orphans, f.Rel)
+			}
+		}
+	}
+	sort.Strings(orphans)
+	return orphans
+}
@@ -0,0 +1,64 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProcessor_Preview(t *testing.T) {
+	tempDir := t.TempDir()
+	excelPath := filepath.Join(tempDir, "test.xlsx")
+	imageDir := filepath.Join(tempDir, "images")
+	_ = os.Mkdir(imageDir, 0755)
+
+	createDummyExcel(excelPath, "Sheet1", "A", []string{"P001", "P002", "P003", "P004", "P001"})
+	_ = createDummyImage(filepath.Join(imageDir, "P001.png"), 10, 20)
+	_ = createDummyImage(filepath.Join(imageDir, "P003.png"), 100, 100)
+	_ = os.WriteFile(filepath.Join(imageDir, "P004.jpg"), []byte("not an image"), 0644)
+	_ = createDummyImage(filepath.Join(imageDir, "UNUSED.png"), 10, 10)
+
+	before, err := os.ReadFile(excelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	p := NewProcessor(excelPath, imageDir, "A", "B", "", 2, 100, 20)
+	p.MaxPixels = 5000
+	preview, err := p.Preview(context.Background())
+	if err != nil {
+		t.Fatalf("Preview() error: %v", err)
+	}
+
+	want := []PreviewRow{
+		{Sheet: "Sheet1", Row: 1, Code: "P001", File: "P001.png", Rule: RuleExact, Width: 10, Height: 20, Problems: []string{ProblemDuplicate}},
+		{Sheet: "Sheet1", Row: 2, Code: "P002", Problems: []string{ProblemMissing}},
+		{Sheet: "Sheet1", Row: 3, Code: "P003", File: "P003.png", Rule: RuleExact, Width: 100, Height: 100, Problems: []string{ProblemOversized}},
+		{Sheet: "Sheet1", Row: 4, Code: "P004", File: "P004.jpg", Rule: RuleExact, Problems: []string{ProblemUndecodable}},
+		{Sheet: "Sheet1", Row: 5, Code: "P001", File: "P001.png", Rule: RuleExact, Width: 10, Height: 20, Problems: []string{ProblemDuplicate}},
+	}
+	if !reflect.DeepEqual(preview.Rows, want) {
+		t.Errorf("Preview rows =\n%+v\nwant\n%+v", preview.Rows, want)
+	}
+	if !reflect.DeepEqual(preview.Orphans, []string{"UNUSED.png"}) {
+		t.Errorf("Preview orphans = %v, want [UNUSED.png]", preview.Orphans)
+	}
+	if !reflect.DeepEqual(p.MissingCodes, []string{"P002"}) {
+		t.Errorf("MissingCodes = %v, want [P002]", p.MissingCodes)
+	}
+
+	// A dry run neither modifies the workbook nor writes an output file
+	after, err := os.ReadFile(excelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(before, after) {
+		t.Error("Preview() modified the workbook")
+	}
+	entries, _ := os.ReadDir(tempDir)
+	if len(entries) != 2 {
+		t.Errorf("Preview() wrote files: %v", entries)
+	}
+}
@@ -2,18 +2,24 @@ package engine
 
 import (
 	"context"
+	"crypto/sha256"
+	"errors"
 	"fmt"
 	"image"
 	_ "image/gif"
 	_ "image/jpeg"
 	_ "image/png"
 	"log"
+	"math"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
 	"time"
 
+	_ "golang.org/x/image/bmp"
+	_ "golang.org/x/image/tiff"
 	_ "golang.org/x/image/webp"
 
 	"github.com/xuri/excelize/v2"
@@ -22,15 +28,34 @@ import (
 type Job struct {
 	ProductCode string
 	ImagePath   string
-	RowIndex    int
+	File        string   // ImagePath relative to the image directory, or absolute outside it
+	Rule        string   // Match rule that paired the code with the file
+	Rows        []RowRef // Every row holding the product code
+	Slot        int      // Position among the images of the code (variants)
+	SlotCount   int      // Number of images placed for the code
+
+	id int // Position in the job list of the run
+}
+
+// Duplicate lists the rows of a sheet sharing a product code
+type Duplicate struct {
+	Sheet string `json:"sheet"`
+	Code  string `json:"code"`
+	Rows  []int  `json:"rows"`
 }
 
 type Result struct {
-	Job      Job
-	ImgBytes []byte
-	Width    int
-	Height   int
-	Err      error
+	Job        Job
+	ImgBytes   []byte
+	Ext        string // Extension of ImgBytes, changes when the image is re-encoded
+	Width      int    // Pixel size of ImgBytes
+	Height     int
+	OrigWidth  int // Pixel size of the source file
+	OrigHeight int
+	OrigSize   int               // Byte size of the source file
+	Hash       [sha256.Size]byte // Content hash of ImgBytes
+	Err        error
+	Elapsed    time.Duration // Time spent loading and resampling the image
 }
 
 type Processor struct {
@@ -43,14 +68,56 @@ type Processor struct {
 	RowHeight   float64
 	ColWidth    float64
 
-	f              *excelize.File
-	productMap     map[string]int
-	jobs           chan Job
-	results        chan Result
-	progressChan   chan float64
-	missingMu      sync.Mutex // Protects MissingCodes
+	// Optional behaviour; zero values keep the defaults
+	Sheets         []SheetTarget // Sheets to process with per-sheet column overrides
+	AllSheets      bool          // Process every worksheet (Sheets entries still override columns)
+	CodeHeader     string        // Header text of the code column, overrides CodeCol
+	ImageHeader    string        // Header text of the image column, overrides ImageCol; created when missing
+	HeaderRow      int           // Rows up to this one are headers and never matched (default 1 with header names)
+	PathCol        string        // Column naming the image file or glob of each row; rows without one match by code
+	Match          MatchOptions
+	Scan           ScanOptions
+	Variants       VariantOptions
+	Resample       ResampleOptions
+	Cache          CacheOptions
+	Validate       ValidateOptions
+	Placement      PlacementOptions
+	Rows           RowOptions
+	Pictures       PictureOptions
+	Info           InfoColumns
+	Existing       string   // What to do with pictures already in the image cells, see ExistingAdd
+	Compatibility  string   // Target application, see CompatModern; unsupported formats are transcoded
+	ConflictPolicy string   // How to pick between files differing only by extension, see ConflictExtension
+	ExtensionOrder []string // Extension preference for ConflictExtension, e.g. [".png", ".jpg"]
+	MaxPixels      int      // Images above this pixel count are rejected (default DefaultMaxPixels)
+
+	f               *excelize.File
+	productMap      map[string][]RowRef
+	rowPaths        map[RowRef]string   // Values of PathCol
+	missingRows     map[string][]RowRef // Rows of MissingCodes without an image
+	sheets          map[string]*sheetInfo
+	cache           *imageCache
+	digitWidth      float64 // Pixel width of a digit in the default font
+	rowHeights      map[RowRef]float64
+	pictureNames    []string // Names of the inserted pictures, see describePicture
+	deletedPictures bool     // Pictures were deleted, see deletePictures
+	jobs            chan Job
+	results         chan Result
+	progressChan    chan float64
+
 	MissingCodes   []string
-	ProcessedCount int // Number of successfully processed images
+	Matches        []Match       // How each matched code was paired with its image
+	Collisions     []Collision   // Filenames found in several folders during a recursive scan
+	Conflicts      []Conflict    // Files competing for the same image in one folder
+	Duplicates     []Duplicate   // Codes found on more than one row of a sheet
+	SheetStats     []SheetStat   // Per-sheet columns and counts
+	ProcessedCount int           // Number of successfully processed images
+	ReplacedCount  int           // Pictures deleted by the Existing policy
+	OutputPath     string        // Path of the workbook written by the last Run
+	Report         *Report       // Per-row outcome of the last Run
+	ReportPath     string        // JSON report written next to the output workbook
+	ReportCSVPath  string        // CSV report written next to the output workbook
+	Quarantined    []Quarantined // Image files rejected by validation
 }
 
 func NewProcessor(excelPath, imageDir, codeCol, imageCol, sheetName string, workerCount int, rowHeight, colWidth float64) *Processor {
@@ -63,7 +130,7 @@ func NewProcessor(excelPath, imageDir, codeCol, imageCol, sheetName string, work
 		WorkerCount:  workerCount,
 		RowHeight:    rowHeight,
 		ColWidth:     colWidth,
-		productMap:   make(map[string]int),
+		productMap:   make(map[string][]RowRef),
 		jobs:         make(chan Job, 100),
 		results:      make(chan Result, 100),
 		MissingCodes: make([]string, 0, 100),
@@ -74,112 +141,116 @@ func (p *Processor) SetProgressChan(ch chan float64) {
 	p.progressChan = ch
 }
 
+// validate checks every option before the workbook is opened
+func (p *Processor) validate() error {
+	if _, err := newMatcher(p.Match); err != nil {
+		return fmt.Errorf("invalid match options: %w", err)
+	}
+	if err := p.Scan.validate(); err != nil {
+		return fmt.Errorf("invalid scan options: %w", err)
+	}
+	if err := p.Variants.validate(); err != nil {
+		return fmt.Errorf("invalid variant options: %w", err)
+	}
+	if err := p.validateConflictPolicy(); err != nil {
+		return err
+	}
+	if err := p.Resample.validate(); err != nil {
+		return fmt.Errorf("invalid resample options: %w", err)
+	}
+	if err := p.validateCompatibility(); err != nil {
+		return err
+	}
+	if err := p.Placement.validate(); err != nil {
+		return fmt.Errorf("invalid placement options: %w", err)
+	}
+	if err := p.Rows.validate(); err != nil {
+		return fmt.Errorf("invalid row options: %w", err)
+	}
+	if err := p.validateExisting(); err != nil {
+		return err
+	}
+	if err := p.Info.validate(); err != nil {
+		return fmt.Errorf("invalid info columns: %w", err)
+	}
+	if err := p.Pictures.validate(); err != nil {
+		return fmt.Errorf("invalid picture options: %w", err)
+	}
+	return nil
+}
+
 func (p *Processor) Run(ctx context.Context) error {
+	if err := p.validate(); err != nil {
+		return err
+	}
+	p.Report = &Report{Workbook: p.ExcelPath, StartedAt: time.Now()}
+	p.pictureNames = nil
+	p.deletedPictures = false
+
 	var err error
 	p.f, err = excelize.OpenFile(p.ExcelPath)
 	if err != nil {
 		return fmt.Errorf("failed to open excel: %w", err)
 	}
 	defer p.f.Close()
+	p.digitWidth = maxDigitWidth(p.f)
 
-	if p.SheetName == "" {
-		p.SheetName = p.f.GetSheetName(0)
+	// 1. Mapping: Resolve the columns of every sheet and read all product codes
+	if err := p.mapSheets(ctx); err != nil {
+		return err
 	}
-
-	// 1. Mapping: Read all product codes using iterator
-	rows, err := p.f.Rows(p.SheetName)
-	if err != nil {
-		return fmt.Errorf("failed to get rows: %w", err)
+	if err := p.writeImageHeaders(); err != nil {
+		return err
 	}
-	defer rows.Close()
 
-	codeColIdx, err := excelize.ColumnNameToNumber(p.CodeCol)
+	// 2. Matching: Index image files and pair them with product codes
+	idx, err := p.indexImages()
 	if err != nil {
-		return fmt.Errorf("invalid code column: %w", err)
+		return err
 	}
-	codeColIdx-- // 0-indexed
-
-	rowIdx := 0
-	for rows.Next() {
-		// Check for cancellation during row processing
-		select {
-		case <-ctx.Done():
-			return ctx.Err()
-		default:
+	jobs := p.matchJobs(idx)
+	p.measureRows(jobs)
+	if p.Existing == ExistingClear {
+		if p.Report.Cleared, err = p.clearImageColumns(jobs); err != nil {
+			return err
 		}
-
-		rowIdx++
-		row, err := rows.Columns()
-		if err != nil {
-			log.Printf("Warning: failed to read columns for row %d: %v", rowIdx, err)
-			continue
+	}
+	existing, err := p.existingPictures()
+	if err != nil {
+		return err
+	}
+	p.Report.Orphans = p.orphanImages(idx)
+	if p.Cache.Dir != "" {
+		if p.cache, err = openCache(p.Cache); err != nil {
+			return err
 		}
-		if len(row) > codeColIdx {
-			code := strings.TrimSpace(row[codeColIdx])
-			if code != "" {
-				p.productMap[code] = rowIdx
+	}
+	for _, code := range p.MissingCodes {
+		for _, ref := range p.missingRows[code] {
+			p.Report.Rows = append(p.Report.Rows, ReportRow{Sheet: ref.Sheet, Row: ref.Row, Code: code, Status: StatusMissing})
+			if p.Rows.Unmatched == UnmatchedResize {
+				if err := p.f.SetRowHeight(ref.Sheet, ref.Row, p.RowHeight); err != nil {
+					return fmt.Errorf("failed to set row height: %w", err)
+				}
 			}
 		}
 	}
 
-	if err := rows.Error(); err != nil {
-		return fmt.Errorf("error reading rows: %w", err)
-	}
-
-	// 2. Start Workers for Image Loading/Scaling
+	// 3. Start Workers for Image Loading/Scaling
 	var wg sync.WaitGroup
 	for i := 0; i < p.WorkerCount; i++ {
 		wg.Add(1)
 		go p.worker(ctx, &wg)
 	}
 
-	// 3. Dispatcher: Scan images and send jobs
-	// 3. Dispatcher: Scan images and send jobs
+	// 4. Dispatcher: Send matched jobs to workers
 	go func() {
 		defer close(p.jobs)
-
-		files, err := os.ReadDir(p.ImageDir)
-		if err != nil {
-			log.Printf("Error reading image directory: %v", err)
-			return
-		}
-
-		// Map files for quick lookup
-		availableImages := make(map[string]string)
-		for _, file := range files {
-			if file.IsDir() {
-				continue
-			}
-			ext := strings.ToLower(filepath.Ext(file.Name()))
-			if ext == ".jpg" || ext == ".jpeg" || ext == ".png" || ext == ".gif" || ext == ".webp" {
-				name := strings.TrimSuffix(file.Name(), filepath.Ext(file.Name()))
-				availableImages[name] = file.Name()
-			}
-		}
-
-		// Dispatch jobs and track missing
-		for code, rowIndex := range p.productMap {
-			// Check for cancellation
+		for _, job := range jobs {
 			select {
+			case p.jobs <- job:
 			case <-ctx.Done():
 				return
-			default:
-			}
-
-			if fileName, ok := availableImages[code]; ok {
-				select {
-				case p.jobs <- Job{
-					ProductCode: code,
-					ImagePath:   filepath.Join(p.ImageDir, fileName),
-					RowIndex:    rowIndex,
-				}:
-				case <-ctx.Done():
-					return
-				}
-			} else {
-				p.missingMu.Lock()
-				p.MissingCodes = append(p.MissingCodes, code)
-				p.missingMu.Unlock()
 			}
 		}
 	}()
@@ -190,58 +261,264 @@ func (p *Processor) Run(ctx context.Context) error {
 		close(p.results)
 	}()
 
-	// 4. Main Loop: Receive results and modify Excel
+	// 5. Main Loop: Receive results and modify Excel
 	p.ProcessedCount = 0
+	p.ReplacedCount = 0
+	done := 0
+	finished := make([]bool, len(jobs))
+	// Content hashes of the embedded pictures. excelize already stores equal
+	// bytes once; the hash makes spotting a repeat cheap and lets the report
+	// count the reused pictures and the bytes they saved.
+	media := make(map[[sha256.Size]byte]bool)
+	rejected := make(map[string]*ImageError)
 
 	// We'll update progress based on results received
 resultLoop:
 	for {
 		select {
 		case <-ctx.Done():
+			p.skipUnfinished(jobs, finished)
 			return ctx.Err()
 		case res, ok := <-p.results:
 			if !ok {
 				break resultLoop // Results channel closed, finishing up
 			}
 
-			if res.Err != nil {
-				log.Printf("Error processing %s: %v", res.Job.ProductCode, res.Err)
-				continue
-			}
+			done++
+			finished[res.Job.id] = true
+			// The image is loaded once and placed on every row of the code
+			for _, ref := range res.Job.Rows {
+				row := ReportRow{
+					Sheet:  ref.Sheet,
+					Row:    ref.Row,
+					Code:   res.Job.ProductCode,
+					File:   res.Job.File,
+					Rule:   res.Job.Rule,
+					Slot:   res.Job.Slot,
+					Width:  res.OrigWidth,
+					Height: res.OrigHeight,
+					Bytes:  res.OrigSize,
+					LoadMs: millis(res.Elapsed),
+				}
+				if res.Err != nil {
+					log.Printf("Error processing %s: %v", res.Job.ProductCode, res.Err)
+					row.Status, row.Error = StatusDecodeError, res.Err.Error()
+					var imgErr *ImageError
+					if errors.As(res.Err, &imgErr) {
+						rejected[res.Job.File] = imgErr
+					}
+					p.Report.Rows = append(p.Report.Rows, row)
+					continue
+				}
 
-			if err := p.insertImageToExcel(res); err != nil {
-				log.Printf("Error inserting %s: %v", res.Job.ProductCode, err)
-				continue
+				_, cell, err := p.imageCell(res.Job, ref)
+				if err == nil && existing[pictureCell{ref.Sheet, cell}] {
+					if p.Existing == ExistingSkip {
+						row.Status, row.Error = StatusSkipped, "cell already has a picture"
+						p.Report.Rows = append(p.Report.Rows, row)
+						continue
+					}
+					if row.Replaced, err = p.deletePictures(ref.Sheet, cell); err != nil {
+						log.Printf("Error deleting pictures at %s!%s: %v", ref.Sheet, cell, err)
+						row.Status, row.Error = StatusInsertError, err.Error()
+						p.Report.Rows = append(p.Report.Rows, row)
+						continue
+					}
+					delete(existing, pictureCell{ref.Sheet, cell}) // Stacked variants share the cell
+				}
+
+				row.Deduplicated = media[res.Hash]
+				start := time.Now()
+				w, h, err := p.insertImageToExcel(res, ref)
+				row.InsertMs = millis(time.Since(start))
+				if err != nil {
+					log.Printf("Error inserting %s at %s row %d: %v", res.Job.ProductCode, ref.Sheet, ref.Row, err)
+					row.Status, row.Error = StatusInsertError, err.Error()
+					p.Report.Rows = append(p.Report.Rows, row)
+					continue
+				}
+				row.Status, row.ScaledWidth, row.ScaledHeight = StatusInserted, w, h
+				row.EmbeddedBytes = len(res.ImgBytes)
+				media[res.Hash] = true
+				p.Report.Rows = append(p.Report.Rows, row)
+				p.ProcessedCount++
+				p.sheets[ref.Sheet].stat.Processed++
 			}
 
-			p.ProcessedCount++
 			if p.progressChan != nil {
 				// Non-blocking send to progress channel to prevent stalling if frontend is slow
 				select {
-				case p.progressChan <- float64(p.ProcessedCount) / float64(len(p.productMap)):
+				case p.progressChan <- float64(done) / float64(len(jobs)):
 				default:
 				}
 			}
 		}
 	}
 
-	// 5. Save result
+	// 6. Save result
+	if err := p.writeInfo(); err != nil {
+		return err
+	}
 	timestamp := time.Now().Format("20060102_150405")
 	outputName := fmt.Sprintf("%s_output_%s.xlsx", strings.TrimSuffix(p.ExcelPath, filepath.Ext(p.ExcelPath)), timestamp)
 	if err := p.f.SaveAs(outputName); err != nil {
 		return fmt.Errorf("failed to save excel: %w", err)
 	}
+	if len(p.pictureNames) > 0 {
+		if err := renamePictures(outputName, p.pictureNames); err != nil {
+			return fmt.Errorf("failed to name pictures: %w", err)
+		}
+	}
+	if p.deletedPictures {
+		if err := pruneMedia(outputName); err != nil {
+			return fmt.Errorf("failed to remove unused pictures: %w", err)
+		}
+	}
+	p.OutputPath = outputName
 
-	// 6. Write log for missing codes
-	if len(p.MissingCodes) > 0 {
-		logPath := fmt.Sprintf("%s_missing_%s.log", strings.TrimSuffix(p.ExcelPath, filepath.Ext(p.ExcelPath)), timestamp)
-		// We ignore error here as it's secondary
-		_ = os.WriteFile(logPath, []byte(strings.Join(p.MissingCodes, "\n")), 0644)
+	// 7. Write the run report; failures are logged as the workbook is already saved
+	if err := p.quarantine(rejected, timestamp); err != nil {
+		log.Printf("Warning: %v", err)
+	}
+	p.Report.Quarantined = p.Quarantined
+	if p.cache != nil {
+		stats := &CacheStats{Hits: int(p.cache.hits.Load()), Misses: int(p.cache.misses.Load())}
+		if stats.Evicted, err = p.cache.prune(); err != nil {
+			log.Printf("Warning: failed to prune image cache: %v", err)
+		}
+		p.Report.Cache = stats
+	}
+	p.Report.Output = outputName
+	p.Report.finish(p.SheetStats)
+	p.ReplacedCount = p.Report.Totals.Replaced
+	base := strings.TrimSuffix(p.ExcelPath, filepath.Ext(p.ExcelPath))
+	p.ReportPath = fmt.Sprintf("%s_report_%s.json", base, timestamp)
+	if err := p.Report.WriteJSON(p.ReportPath); err != nil {
+		log.Printf("Warning: failed to write report: %v", err)
+		p.ReportPath = ""
+	}
+	p.ReportCSVPath = fmt.Sprintf("%s_report_%s.csv", base, timestamp)
+	if err := p.Report.WriteCSV(p.ReportCSVPath); err != nil {
+		log.Printf("Warning: failed to write report: %v", err)
+		p.ReportCSVPath = ""
 	}
 
 	return nil
 }
 
+// skipUnfinished records every row of the jobs without a result as skipped
+func (p *Processor) skipUnfinished(jobs []Job, finished []bool) {
+	for i, job := range jobs {
+		if finished[i] {
+			continue
+		}
+		for _, ref := range job.Rows {
+			p.Report.Rows = append(p.Report.Rows, ReportRow{
+				Sheet:  ref.Sheet,
+				Row:    ref.Row,
+				Code:   job.ProductCode,
+				File:   job.File,
+				Rule:   job.Rule,
+				Slot:   job.Slot,
+				Status: StatusSkipped,
+			})
+		}
+	}
+	p.Report.finish(p.SheetStats)
+}
+
+// matchJobs pairs every mapped product code with an image file. Rows naming
+// their image in PathCol use it; the other rows, and those whose path finds no
+// file, match by code. Codes are visited in sorted order so MissingCodes and
+// Matches are deterministic.
+func (p *Processor) matchJobs(idx *imageIndex) []Job {
+	codes := make([]string, 0, len(p.productMap))
+	for code := range p.productMap {
+		codes = append(codes, code)
+	}
+	sort.Strings(codes)
+
+	jobs := make([]Job, 0, len(codes))
+	p.missingRows = make(map[string][]RowRef)
+	for _, code := range codes {
+		refs := p.productMap[code]
+		byPath := make(map[string][]RowRef)
+		var paths []string
+		var byCode []RowRef
+		for _, ref := range refs {
+			value, ok := p.rowPaths[ref]
+			if !ok {
+				byCode = append(byCode, ref)
+				continue
+			}
+			if _, seen := byPath[value]; !seen {
+				paths = append(paths, value)
+			}
+			byPath[value] = append(byPath[value], ref)
+		}
+		for _, value := range paths {
+			files := p.resolvePath(value)
+			if len(files) == 0 {
+				log.Printf("Warning: no image found at '%s' for %s, matching the code instead", value, code)
+				byCode = append(byCode, byPath[value]...)
+				continue
+			}
+			match := Match{Code: code, File: files[0], Variants: files[1:], Rule: RulePath, Rows: byPath[value]}
+			jobs = p.addMatch(jobs, match)
+		}
+		if len(byCode) == 0 {
+			continue
+		}
+
+		files, rule, ok := idx.lookup(code)
+		if !ok {
+			p.MissingCodes = append(p.MissingCodes, code)
+			p.missingRows[code] = byCode
+			counted := make(map[string]bool)
+			for _, ref := range byCode {
+				if !counted[ref.Sheet] {
+					counted[ref.Sheet] = true
+					p.sheets[ref.Sheet].stat.Missing++
+				}
+			}
+			continue
+		}
+
+		match := Match{Code: code, File: files[0].Rel, Rule: rule}
+		for _, f := range files[1:] {
+			match.Variants = append(match.Variants, f.Rel)
+		}
+		if len(byCode) < len(refs) {
+			match.Rows = byCode
+		}
+		jobs = p.addMatch(jobs, match)
+	}
+	return jobs
+}
+
+// addMatch records a match and appends a job for each of its files
+func (p *Processor) addMatch(jobs []Job, match Match) []Job {
+	p.Matches = append(p.Matches, match)
+	rows := match.Rows
+	if rows == nil {
+		rows = p.productMap[match.Code]
+	}
+	files := append([]string{match.File}, match.Variants...)
+	for slot, f := range files {
+		jobs = append(jobs, Job{
+			ProductCode: match.Code,
+			ImagePath:   p.imagePath(f),
+			File:        f,
+			Rule:        match.Rule,
+			Rows:        rows,
+			Slot:        slot,
+			SlotCount:   len(files),
+			id:          len(jobs),
+		})
+	}
+	return jobs
+}
+
 func (p *Processor) worker(ctx context.Context, wg *sync.WaitGroup) {
 	defer wg.Done()
 	for {
@@ -252,15 +529,14 @@ func (p *Processor) worker(ctx context.Context, wg *sync.WaitGroup) {
 			if !ok {
 				return
 			}
-			imgBytes, w, h, err := p.loadImageData(job.ImagePath)
+			start := time.Now()
+			res := p.prepareImage(job)
+			if res.Err == nil {
+				res.Hash = sha256.Sum256(res.ImgBytes)
+			}
+			res.Elapsed = time.Since(start)
 			select {
-			case p.results <- Result{
-				Job:      job,
-				ImgBytes: imgBytes,
-				Width:    w,
-				Height:   h,
-				Err:      err,
-			}:
+			case p.results <- res:
 			case <-ctx.Done():
 				return
 			}
@@ -286,51 +562,147 @@ func (p *Processor) loadImageData(path string) ([]byte, int, int, error) {
 		return nil, 0, 0, err
 	}
 
-	return imgBytes, imgConfig.Width, imgConfig.Height, nil
+	// Report the displayed size of rotated photos
+	w, h := orientedSize(imgConfig.Width, imgConfig.Height, exifOrientation(imgBytes))
+	return imgBytes, w, h, nil
 }
 
-func (p *Processor) insertImageToExcel(res Result) error {
-	colIdx, err := excelize.ColumnNameToNumber(p.ImageCol)
-	if err != nil {
-		return fmt.Errorf("invalid image column '%s': %w", p.ImageCol, err)
+// prepareImage loads the image of job and, unless the originals are kept,
+// resamples it to the pixel box of its cell. Formats the compatibility target
+// cannot display are always transcoded.
+func (p *Processor) prepareImage(job Job) Result {
+	res := Result{Job: job, Ext: filepath.Ext(job.ImagePath)}
+	if res.Err = p.checkFileSize(job.ImagePath); res.Err != nil {
+		return res
+	}
+	imgBytes, w, h, err := p.loadImageData(job.ImagePath)
+	if res.Err = p.checkImage(err, w, h); res.Err != nil {
+		return res
+	}
+	res.ImgBytes, res.Width, res.Height = imgBytes, w, h
+	res.OrigWidth, res.OrigHeight, res.OrigSize = w, h, len(imgBytes)
+
+	displayable := p.displayable(res.Ext)
+	boxW, boxH, _, _ := p.cellBox(job, p.jobRowHeight(job))
+	opts := p.Resample
+	if opts.KeepOriginal {
+		if displayable && exifOrientation(imgBytes) == 1 && !opts.Trim && !opts.Pad && (opts.KeepColors || !needsSRGB(imgBytes)) {
+			if p.Validate.FullDecode {
+				res.Err = fullDecode(imgBytes)
+			}
+			return res
+		}
+		// Rotated, framed, CMYK and wide-gamut photos and formats the target
+		// cannot show are still re-encoded; an infinite DPI scale keeps their
+		// full size
+		opts.DPIScale = math.Inf(1)
 	}
-	cellName, err := excelize.CoordinatesToCellName(colIdx, res.Job.RowIndex)
+
+	img, err := p.resampleCached(opts, imgBytes, res.Ext, boxW, boxH, displayable)
 	if err != nil {
-		return fmt.Errorf("failed to get cell name: %w", err)
+		res.Err = err
+		return res
 	}
+	res.ImgBytes, res.Ext, res.Width, res.Height = img.data, img.ext, img.width, img.height
+	return res
+}
 
-	// Set Row Height and Col Width from Processor settings
-	if err = p.f.SetRowHeight(p.SheetName, res.Job.RowIndex, p.RowHeight); err != nil {
-		return fmt.Errorf("failed to set row height: %w", err)
+// resampleCached returns the resampled image from the cache when possible and
+// stores newly resampled images in it
+func (p *Processor) resampleCached(opts ResampleOptions, data []byte, ext string, boxW, boxH float64, keepable bool) (resampled, error) {
+	if p.cache == nil {
+		return opts.resample(data, ext, boxW, boxH, keepable)
 	}
-	if err = p.f.SetColWidth(p.SheetName, p.ImageCol, p.ImageCol, p.ColWidth); err != nil {
-		return fmt.Errorf("failed to set col width: %w", err)
+	key := cacheKey(data, ext, boxW, boxH, keepable, opts)
+	if img, ok := p.cache.get(key); ok {
+		return img, nil
 	}
+	img, err := opts.resample(data, ext, boxW, boxH, keepable)
+	if err != nil {
+		return img, err
+	}
+	if err := p.cache.put(key, img); err != nil {
+		log.Printf("Warning: failed to cache image: %v", err)
+	}
+	return img, nil
+}
 
-	// Calculate scale to fit. Default rowHeight 105pt is ~140px. colWidth 20 is ~145px
-	// We'll use a margin of 10px
-	targetH := p.RowHeight*1.333 - 10
-	targetW := p.ColWidth*7.0 - 10 // Approximation for column width in pixels
-
-	scaleX := targetW / float64(res.Width)
-	scaleY := targetH / float64(res.Height)
+// cellBox returns the pixel box a picture of job is fitted into on a row of
+// the given height and the position of that box inside the cell, leaving the
+// margins free
+func (p *Processor) cellBox(job Job, rowHeight float64) (float64, float64, int, int) {
+	marginX, marginY := p.Placement.margins()
+	cellH := rowHeightPixels(rowHeight)
+	boxW := max(1, p.cellWidth()-float64(2*marginX))
+	boxH := max(1, cellH-float64(2*marginY))
+	y := marginY
+
+	// Stacked variants share the cell height equally
+	if p.Variants.Layout == LayoutStack && job.SlotCount > 1 {
+		slotH := (cellH - float64(marginY)) / float64(job.SlotCount)
+		boxH = max(1, slotH-float64(marginY))
+		y += int(slotH * float64(job.Slot))
+	}
+	return boxW, boxH, marginX, y
+}
 
-	scale := scaleX
-	if scaleY < scale {
-		scale = scaleY
+// insertImageToExcel places the picture of res on one row and returns its
+// displayed pixel size
+func (p *Processor) insertImageToExcel(res Result, ref RowRef) (int, int, error) {
+	colName, cellName, err := p.imageCell(res.Job, ref)
+	if err != nil {
+		return 0, 0, err
 	}
 
-	err = p.f.AddPictureFromBytes(p.SheetName, cellName, &excelize.Picture{
-		Extension: filepath.Ext(res.Job.ImagePath),
+	// Scale to fit the box and align inside it
+	rowHeight := p.rowHeight(ref)
+	boxW, boxH, x, y := p.cellBox(res.Job, rowHeight)
+	scale := math.Min(boxW/float64(res.Width), boxH/float64(res.Height))
+	w, h := int(float64(res.Width)*scale), int(float64(res.Height)*scale)
+	offsetX, offsetY := p.Placement.align(x, y, boxW, boxH, w, h)
+
+	// excelize finds the end cell of two-cell anchors from its own estimate of
+	// row and column sizes, which is smaller than Excel's. The picture always
+	// fits its cell, so the cell is made as large as possible while adding it.
+	positioning := p.Placement.positioning()
+	if positioning != PositionOneCell {
+		if err := p.setCellSize(ref.Sheet, ref.Row, colName, DefaultMaxRowHeight, maxColWidth); err != nil {
+			return 0, 0, err
+		}
+	}
+	format := &excelize.GraphicOptions{
+		ScaleX:      scale,
+		ScaleY:      scale,
+		OffsetX:     offsetX,
+		OffsetY:     offsetY,
+		Positioning: positioning,
+	}
+	if err := p.describePicture(format, res, ref); err != nil {
+		return 0, 0, err
+	}
+	addErr := p.f.AddPictureFromBytes(ref.Sheet, cellName, &excelize.Picture{
+		Extension: res.Ext,
 		File:      res.ImgBytes,
-		Format: &excelize.GraphicOptions{
-			ScaleX:      scale,
-			ScaleY:      scale,
-			OffsetX:     5,
-			OffsetY:     5,
-			Positioning: "oneCell",
-		},
+		Format:    format,
 	})
 
-	return err
+	// Set Row Height and Col Width from Processor settings
+	if err := p.setCellSize(ref.Sheet, ref.Row, colName, rowHeight, p.ColWidth); err != nil {
+		return 0, 0, err
+	}
+	if addErr != nil {
+		return 0, 0, addErr
+	}
+	return w, h, nil
+}
+
+// setCellSize sets the height of a row and the width of a column
+func (p *Processor) setCellSize(sheet string, row int, col string, height, width float64) error {
+	if err := p.f.SetRowHeight(sheet, row, height); err != nil {
+		return fmt.Errorf("failed to set row height: %w", err)
+	}
+	if err := p.f.SetColWidth(sheet, col, col, width); err != nil {
+		return fmt.Errorf("failed to set col width: %w", err)
+	}
+	return nil
 }
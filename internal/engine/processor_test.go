@@ -8,6 +8,8 @@ import (
 	"image/png"
 	"os"
 	"path/filepath"
+	"reflect"
+	"strings"
 	"testing"
 
 	"github.com/xuri/excelize/v2"
@@ -137,6 +139,39 @@ func TestProcessor_Run(t *testing.T) {
 	}
 }
 
+func TestProcessor_Validate(t *testing.T) {
+	tests := []struct {
+		name  string
+		setup func(p *Processor)
+		want  string
+	}{
+		{"match", func(p *Processor) { p.Match.Pattern = "IMG_front" }, "invalid match options"},
+		{"scan", func(p *Processor) { p.Scan.Include = []string{"[a"} }, "invalid scan options"},
+		{"variants", func(p *Processor) { p.Variants.Layout = "grid" }, "invalid variant options"},
+		{"conflict", func(p *Processor) { p.ConflictPolicy = "largest" }, "invalid conflict policy"},
+		{"resample", func(p *Processor) { p.Resample.Background = "red" }, "invalid resample options"},
+		{"compatibility", func(p *Processor) { p.Compatibility = "lotus" }, "unknown compatibility target"},
+		{"placement", func(p *Processor) { p.Placement.MarginX = ptr(-1) }, "invalid placement options"},
+		{"rows", func(p *Processor) { p.Rows.Mode = "tall" }, "invalid row options"},
+		{"existing", func(p *Processor) { p.Existing = "merge" }, "invalid existing picture policy"},
+		{"info", func(p *Processor) { p.Info.File = "1" }, "invalid info columns"},
+		{"pictures", func(p *Processor) { p.Pictures.Name = "{sku}" }, "invalid picture options"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// The workbook does not exist, so only validation can fail first
+			p := NewProcessor(filepath.Join(t.TempDir(), "missing.xlsx"), t.TempDir(), "A", "B", "Sheet1", 1, 100, 20)
+			tt.setup(p)
+			if err := p.Run(context.Background()); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Run() error = %v, want %q", err, tt.want)
+			}
+			if _, err := p.Preview(context.Background()); err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Errorf("Preview() error = %v, want %q", err, tt.want)
+			}
+		})
+	}
+}
+
 func TestProcessor_LoadImageData(t *testing.T) {
 	tempDir, _ := os.MkdirTemp("", "img_test")
 	defer os.RemoveAll(tempDir)
@@ -157,3 +192,77 @@ func TestProcessor_LoadImageData(t *testing.T) {
 		t.Error("Returned empty bytes")
 	}
 }
+
+func TestProcessor_RunDuplicateCodes(t *testing.T) {
+	tempDir := t.TempDir()
+	excelPath := filepath.Join(tempDir, "test.xlsx")
+	imageDir := filepath.Join(tempDir, "images")
+	_ = os.Mkdir(imageDir, 0755)
+
+	products := []string{"P001", "P002", "P001", "P003", "P001"}
+	if err := createDummyExcel(excelPath, "Sheet1", "A", products); err != nil {
+		t.Fatal(err)
+	}
+	_ = createDummyImage(filepath.Join(imageDir, "P001.png"), 30, 30)
+	_ = createDummyImage(filepath.Join(imageDir, "P002.png"), 30, 30)
+
+	p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 2, 100, 20)
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if p.ProcessedCount != 4 {
+		t.Errorf("expected 4 inserted pictures, got %d", p.ProcessedCount)
+	}
+	if len(p.Duplicates) != 1 || p.Duplicates[0].Code != "P001" || !reflect.DeepEqual(p.Duplicates[0].Rows, []int{1, 3, 5}) {
+		t.Errorf("unexpected duplicates: %+v", p.Duplicates)
+	}
+
+	f, err := excelize.OpenFile(p.OutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, cell := range []string{"B1", "B2", "B3", "B5"} {
+		pics, err := f.GetPictures("Sheet1", cell)
+		if err != nil || len(pics) != 1 {
+			t.Errorf("expected one picture in %s, got %d (err: %v)", cell, len(pics), err)
+		}
+	}
+}
+
+func TestProcessor_RunDeduplicatesIdenticalImages(t *testing.T) {
+	tempDir := t.TempDir()
+	excelPath := filepath.Join(tempDir, "test.xlsx")
+	imageDir := filepath.Join(tempDir, "images")
+	_ = os.Mkdir(imageDir, 0755)
+
+	if err := createDummyExcel(excelPath, "Sheet1", "A", []string{"P001", "P002", "P003", "P004"}); err != nil {
+		t.Fatal(err)
+	}
+	// P001-P003 share a placeholder photo
+	for _, code := range []string{"P001", "P002", "P003"} {
+		_ = createDummyImage(filepath.Join(imageDir, code+".png"), 300, 300)
+	}
+	_ = createDummyImage(filepath.Join(imageDir, "P004.png"), 300, 200)
+
+	p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 4, 100, 20)
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	totals := p.Report.Totals
+	if p.ProcessedCount != 4 || totals.Deduplicated != 2 {
+		t.Errorf("processed %d, deduplicated %d; want 4 and 2", p.ProcessedCount, totals.Deduplicated)
+	}
+	if totals.DeduplicatedBytes <= 0 || totals.DeduplicatedBytes != 2*int64(p.Report.Rows[0].EmbeddedBytes) {
+		t.Errorf("unexpected deduplicated bytes: %+v", totals)
+	}
+
+	// Whichever shared photo is inserted first is stored, the others reuse it
+	for _, row := range p.Report.Rows {
+		if row.Deduplicated && row.Code == "P004" {
+			t.Error("P004 is marked as deduplicated but has a distinct image")
+		}
+	}
+}
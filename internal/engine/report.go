@@ -0,0 +1,183 @@
+package engine
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// Row outcomes recorded in the run report
+const (
+	StatusInserted    = "inserted"     // The picture was placed
+	StatusMissing     = "missing"      // No image matches the code
+	StatusDecodeError = "decode_error" // The image could not be read or decoded
+	StatusInsertError = "insert_error" // The picture could not be added to the sheet
+	StatusSkipped     = "skipped"      // The picture was not placed, e.g. the run was cancelled or the cell had one
+)
+
+// ReportRow is the outcome of one picture placement, or of a row without image
+type ReportRow struct {
+	Sheet         string  `json:"sheet"`
+	Row           int     `json:"row"`
+	Code          string  `json:"code"`
+	File          string  `json:"file,omitempty"` // Path relative to the image directory
+	Rule          string  `json:"rule,omitempty"` // Match rule that paired the code with File
+	Slot          int     `json:"slot"`           // Variant position, 0 for the main image
+	Status        string  `json:"status"`
+	Error         string  `json:"error,omitempty"`
+	Width         int     `json:"width"`         // Original pixel size
+	Height        int     `json:"height"`        // Original pixel size
+	ScaledWidth   int     `json:"scaledWidth"`   // Displayed pixel size in the cell
+	ScaledHeight  int     `json:"scaledHeight"`  // Displayed pixel size in the cell
+	Bytes         int     `json:"bytes"`         // Size of the source file
+	EmbeddedBytes int     `json:"embeddedBytes"` // Size of the picture stored in the workbook
+	LoadMs        float64 `json:"loadMs"`        // Time spent reading and resampling the image
+	InsertMs      float64 `json:"insertMs"`      // Time spent adding the picture
+	Deduplicated  bool    `json:"deduplicated"`  // Reuses a picture already stored in the workbook
+	Replaced      int     `json:"replaced"`      // Pictures deleted from the cell before placing this one
+}
+
+// ReportTotals counts the report rows by status
+type ReportTotals struct {
+	Rows         int `json:"rows"`
+	Inserted     int `json:"inserted"`
+	Missing      int `json:"missing"`
+	DecodeErrors int `json:"decodeErrors"`
+	InsertErrors int `json:"insertErrors"`
+	Skipped      int `json:"skipped"`
+	Orphans      int `json:"orphans"`
+	Replaced     int `json:"replaced"` // Existing pictures deleted by the re-run policy
+
+	// Byte sizes of the inserted pictures before and after resampling and
+	// deduplication. EmbeddedBytes counts each stored picture once.
+	OriginalBytes int64 `json:"originalBytes"`
+	EmbeddedBytes int64 `json:"embeddedBytes"`
+	SavedBytes    int64 `json:"savedBytes"`
+
+	// Pictures that reuse identical content stored for another cell
+	Deduplicated      int   `json:"deduplicated"`
+	DeduplicatedBytes int64 `json:"deduplicatedBytes"`
+}
+
+// Report is the machine-readable outcome of a run
+type Report struct {
+	Workbook    string        `json:"workbook"`
+	Output      string        `json:"output"`
+	StartedAt   time.Time     `json:"startedAt"`
+	FinishedAt  time.Time     `json:"finishedAt"`
+	ElapsedMs   float64       `json:"elapsedMs"`
+	Totals      ReportTotals  `json:"totals"`
+	Rows        []ReportRow   `json:"rows"`
+	Orphans     []string      `json:"orphans"`         // Images no product code matched
+	Cache       *CacheStats   `json:"cache,omitempty"` // Set when the image cache is enabled
+	Quarantined []Quarantined `json:"quarantined"`     // Image files rejected by validation
+	Cleared     int           `json:"cleared"`         // Pictures deleted from the image columns before placing any
+}
+
+// reportHeader lists the CSV columns, in ReportRow field order
+var reportHeader = []string{
+	"sheet", "row", "code", "file", "rule", "slot", "status", "error",
+	"width", "height", "scaled_width", "scaled_height", "bytes", "embedded_bytes", "load_ms", "insert_ms", "deduplicated", "replaced",
+}
+
+// finish sorts the rows by sheet order, row and slot and fills the totals
+func (r *Report) finish(sheetOrder []SheetStat) {
+	order := make(map[string]int, len(sheetOrder))
+	for i, s := range sheetOrder {
+		order[s.Name] = i
+	}
+	sort.SliceStable(r.Rows, func(i, j int) bool {
+		a, b := r.Rows[i], r.Rows[j]
+		if a.Sheet != b.Sheet {
+			return order[a.Sheet] < order[b.Sheet]
+		}
+		if a.Row != b.Row {
+			return a.Row < b.Row
+		}
+		return a.Slot < b.Slot
+	})
+
+	r.Totals = ReportTotals{Rows: len(r.Rows), Orphans: len(r.Orphans), Replaced: r.Cleared}
+	for _, row := range r.Rows {
+		r.Totals.Replaced += row.Replaced
+		switch row.Status {
+		case StatusInserted:
+			r.Totals.Inserted++
+			r.Totals.OriginalBytes += int64(row.Bytes)
+			if row.Deduplicated {
+				r.Totals.Deduplicated++
+				r.Totals.DeduplicatedBytes += int64(row.EmbeddedBytes)
+			} else {
+				r.Totals.EmbeddedBytes += int64(row.EmbeddedBytes)
+			}
+		case StatusMissing:
+			r.Totals.Missing++
+		case StatusDecodeError:
+			r.Totals.DecodeErrors++
+		case StatusInsertError:
+			r.Totals.InsertErrors++
+		case StatusSkipped:
+			r.Totals.Skipped++
+		}
+	}
+	r.Totals.SavedBytes = r.Totals.OriginalBytes - r.Totals.EmbeddedBytes
+	r.FinishedAt = time.Now()
+	r.ElapsedMs = millis(r.FinishedAt.Sub(r.StartedAt))
+}
+
+// WriteJSON writes the report as indented JSON
+func (r *Report) WriteJSON(path string) error {
+	data, err := json.MarshalIndent(r, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode report: %w", err)
+	}
+	return os.WriteFile(path, data, 0644)
+}
+
+// WriteCSV writes one line per report row
+func (r *Report) WriteCSV(path string) error {
+	file, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	w := csv.NewWriter(file)
+	_ = w.Write(reportHeader)
+	for _, row := range r.Rows {
+		_ = w.Write([]string{
+			row.Sheet,
+			strconv.Itoa(row.Row),
+			row.Code,
+			row.File,
+			row.Rule,
+			strconv.Itoa(row.Slot),
+			row.Status,
+			row.Error,
+			strconv.Itoa(row.Width),
+			strconv.Itoa(row.Height),
+			strconv.Itoa(row.ScaledWidth),
+			strconv.Itoa(row.ScaledHeight),
+			strconv.Itoa(row.Bytes),
+			strconv.Itoa(row.EmbeddedBytes),
+			strconv.FormatFloat(row.LoadMs, 'f', 2, 64),
+			strconv.FormatFloat(row.InsertMs, 'f', 2, 64),
+			strconv.FormatBool(row.Deduplicated),
+			strconv.Itoa(row.Replaced),
+		})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return err
+	}
+	return file.Close()
+}
+
+// millis converts a duration to fractional milliseconds
+func millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
@@ -0,0 +1,107 @@
+package engine
+
+import (
+	"context"
+	"encoding/csv"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProcessor_RunReport(t *testing.T) {
+	tempDir := t.TempDir()
+	excelPath := filepath.Join(tempDir, "test.xlsx")
+	imageDir := filepath.Join(tempDir, "images")
+	_ = os.Mkdir(imageDir, 0755)
+
+	if err := createDummyExcel(excelPath, "Sheet1", "A", []string{"P001", "P002", "P003", "P001"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = createDummyImage(filepath.Join(imageDir, "P001.png"), 200, 100)
+	_ = os.WriteFile(filepath.Join(imageDir, "P003.jpg"), []byte("broken"), 0644)
+	_ = createDummyImage(filepath.Join(imageDir, "EXTRA.png"), 10, 10)
+
+	p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 2, 100, 20)
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	r := p.Report
+	// P001 is placed twice but stored once
+	wantTotals := ReportTotals{Rows: 4, Inserted: 2, Missing: 1, DecodeErrors: 1, Orphans: 1, Deduplicated: 1}
+	wantTotals.OriginalBytes, wantTotals.EmbeddedBytes, wantTotals.SavedBytes = r.Totals.OriginalBytes, r.Totals.EmbeddedBytes, r.Totals.SavedBytes
+	wantTotals.DeduplicatedBytes = r.Totals.EmbeddedBytes
+	if r.Totals != wantTotals {
+		t.Errorf("report totals = %+v, want %+v", r.Totals, wantTotals)
+	}
+	var statuses []string
+	for _, row := range r.Rows {
+		statuses = append(statuses, row.Status)
+	}
+	if want := []string{StatusInserted, StatusMissing, StatusDecodeError, StatusInserted}; !reflect.DeepEqual(statuses, want) {
+		t.Errorf("row statuses = %v, want %v", statuses, want)
+	}
+	first := r.Rows[0]
+	if first.File != "P001.png" || first.Rule != RuleExact || first.Width != 200 || first.Height != 100 || first.ScaledWidth != 130 || first.ScaledHeight != 65 {
+		t.Errorf("unexpected inserted row: %+v", first)
+	}
+	if r.Rows[1].Rule != "" {
+		t.Errorf("missing row has rule %q", r.Rows[1].Rule)
+	}
+	if r.Rows[2].Error == "" {
+		t.Error("decode error row has no error text")
+	}
+	if !reflect.DeepEqual(r.Orphans, []string{"EXTRA.png"}) {
+		t.Errorf("report orphans = %v, want [EXTRA.png]", r.Orphans)
+	}
+
+	data, err := os.ReadFile(p.ReportPath)
+	if err != nil {
+		t.Fatalf("JSON report not written: %v", err)
+	}
+	var decoded Report
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.Totals != wantTotals {
+		t.Errorf("JSON report totals = %+v (err: %v)", decoded.Totals, err)
+	}
+
+	file, err := os.Open(p.ReportCSVPath)
+	if err != nil {
+		t.Fatalf("CSV report not written: %v", err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(records) != 5 || !reflect.DeepEqual(records[0], reportHeader) || records[1][4] != RuleExact || records[2][6] != StatusMissing {
+		t.Errorf("unexpected CSV report: %v", records)
+	}
+}
+
+func TestReport_Finish(t *testing.T) {
+	r := &Report{
+		Rows: []ReportRow{
+			{Sheet: "B", Row: 1, Status: StatusInserted},
+			{Sheet: "A", Row: 2, Slot: 1, Status: StatusSkipped},
+			{Sheet: "A", Row: 2, Slot: 0, Status: StatusInsertError},
+			{Sheet: "A", Row: 1, Status: StatusMissing},
+		},
+		Orphans: []string{"x.png"},
+	}
+	r.finish([]SheetStat{{Name: "A"}, {Name: "B"}})
+
+	var order []string
+	for _, row := range r.Rows {
+		order = append(order, row.Status)
+	}
+	want := []string{StatusMissing, StatusInsertError, StatusSkipped, StatusInserted}
+	if !reflect.DeepEqual(order, want) {
+		t.Errorf("row order = %v, want %v", order, want)
+	}
+	wantTotals := ReportTotals{Rows: 4, Inserted: 1, Missing: 1, InsertErrors: 1, Skipped: 1, Orphans: 1}
+	if r.Totals != wantTotals {
+		t.Errorf("totals = %+v, want %+v", r.Totals, wantTotals)
+	}
+}
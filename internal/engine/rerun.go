@@ -0,0 +1,204 @@
+package engine
+
+import (
+	"archive/zip"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"path"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// Policies for pictures already in the workbook, Processor.Existing
+const (
+	ExistingAdd     = "add"     // Add new pictures on top of existing ones (default)
+	ExistingSkip    = "skip"    // Leave cells that already have a picture alone
+	ExistingReplace = "replace" // Delete the pictures of a cell before placing the new one
+	ExistingClear   = "clear"   // Delete every picture anchored in the image columns first
+)
+
+// validateExisting checks the re-run policy
+func (p *Processor) validateExisting() error {
+	switch p.Existing {
+	case "", ExistingAdd, ExistingSkip, ExistingReplace, ExistingClear:
+		return nil
+	}
+	return errors.New("invalid existing picture policy '" + p.Existing + "'")
+}
+
+// imageCell returns the column and cell the picture of job goes to on ref
+func (p *Processor) imageCell(job Job, ref RowRef) (string, string, error) {
+	imageCol := p.sheets[ref.Sheet].ImageCol
+	colIdx, err := excelize.ColumnNameToNumber(imageCol)
+	if err != nil {
+		return "", "", fmt.Errorf("invalid image column '%s': %w", imageCol, err)
+	}
+
+	// Variants go into the next columns unless they are stacked in one cell
+	stacked := p.Variants.Layout == LayoutStack && job.SlotCount > 1
+	if !stacked {
+		colIdx += job.Slot
+	}
+	colName, err := excelize.ColumnNumberToName(colIdx)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get image column: %w", err)
+	}
+	cellName, err := excelize.CoordinatesToCellName(colIdx, ref.Row)
+	if err != nil {
+		return "", "", fmt.Errorf("failed to get cell name: %w", err)
+	}
+	return colName, cellName, nil
+}
+
+// pictureCell is a cell of a sheet
+type pictureCell struct {
+	Sheet string
+	Cell  string
+}
+
+// existingPictures returns the cells of the target sheets that held a
+// picture before the run, for the skip and replace policies
+func (p *Processor) existingPictures() (map[pictureCell]bool, error) {
+	cells := make(map[pictureCell]bool)
+	if p.Existing != ExistingSkip && p.Existing != ExistingReplace {
+		return cells, nil
+	}
+	for name := range p.sheets {
+		found, err := p.f.GetPictureCells(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to list pictures of sheet '%s': %w", name, err)
+		}
+		for _, cell := range found {
+			cells[pictureCell{name, cell}] = true
+		}
+	}
+	return cells, nil
+}
+
+// deletePictures deletes the pictures anchored in a cell and returns how many there were.
+//
+// DeletePicture removes media it considers unused, but misses other pictures
+// of the same drawing sharing it, and excelize numbers new media by counting
+// the stored ones, so a later picture would overwrite an existing file. The
+// media is therefore kept and pruneMedia removes what is unused after saving.
+func (p *Processor) deletePictures(sheet, cell string) (int, error) {
+	pics, err := p.f.GetPictures(sheet, cell)
+	if err != nil || len(pics) == 0 {
+		return 0, err
+	}
+	media := make(map[string]any)
+	p.f.Pkg.Range(func(k, v any) bool {
+		if name := k.(string); strings.HasPrefix(name, "xl/media/") {
+			media[name] = v
+		}
+		return true
+	})
+	err = p.f.DeletePicture(sheet, cell)
+	for name, data := range media {
+		p.f.Pkg.LoadOrStore(name, data)
+	}
+	if err != nil {
+		return 0, err
+	}
+	p.deletedPictures = true
+	return len(pics), nil
+}
+
+// clearImageColumns deletes every picture anchored in the image columns of
+// the target sheets, including the columns of variants, and returns how many
+// were deleted
+func (p *Processor) clearImageColumns(jobs []Job) (int, error) {
+	columns := make(map[string]map[int]bool)
+	add := func(sheet string, col int) {
+		if columns[sheet] == nil {
+			columns[sheet] = make(map[int]bool)
+		}
+		columns[sheet][col] = true
+	}
+	for name, info := range p.sheets {
+		col, err := excelize.ColumnNameToNumber(info.ImageCol)
+		if err != nil {
+			return 0, fmt.Errorf("invalid image column '%s': %w", info.ImageCol, err)
+		}
+		add(name, col)
+	}
+	for _, job := range jobs {
+		for _, ref := range job.Rows {
+			if _, cell, err := p.imageCell(job, ref); err == nil {
+				col, _, _ := excelize.CellNameToCoordinates(cell)
+				add(ref.Sheet, col)
+			}
+		}
+	}
+
+	deleted := 0
+	for sheet, cols := range columns {
+		cells, err := p.f.GetPictureCells(sheet)
+		if err != nil {
+			return deleted, fmt.Errorf("failed to list pictures of sheet '%s': %w", sheet, err)
+		}
+		for _, cell := range cells {
+			col, _, err := excelize.CellNameToCoordinates(cell)
+			if err != nil || !cols[col] {
+				continue
+			}
+			n, err := p.deletePictures(sheet, cell)
+			if err != nil {
+				return deleted, fmt.Errorf("failed to delete pictures at %s!%s: %w", sheet, cell, err)
+			}
+			deleted += n
+		}
+	}
+	return deleted, nil
+}
+
+// pruneMedia removes the media files of the workbook at path that no
+// relationship refers to any more, see deletePictures
+func pruneMedia(file string) error {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return err
+	}
+	used := make(map[string]bool)
+	for _, f := range zr.File {
+		if !strings.HasSuffix(f.Name, ".rels") {
+			continue
+		}
+		var rels struct {
+			Relationships []struct {
+				Target     string `xml:",attr"`
+				TargetMode string `xml:",attr"`
+			} `xml:"Relationship"`
+		}
+		rc, err := f.Open()
+		if err != nil {
+			zr.Close()
+			return err
+		}
+		err = xml.NewDecoder(io.LimitReader(rc, 64<<20)).Decode(&rels)
+		rc.Close()
+		if err != nil {
+			zr.Close()
+			return fmt.Errorf("failed to read %s: %w", f.Name, err)
+		}
+		// Targets are relative to the folder of the part owning the _rels folder
+		dir := path.Dir(path.Dir(f.Name))
+		for _, rel := range rels.Relationships {
+			if rel.TargetMode == "External" {
+				continue
+			}
+			if strings.HasPrefix(rel.Target, "/") {
+				used[strings.TrimPrefix(rel.Target, "/")] = true
+			} else {
+				used[path.Join(dir, rel.Target)] = true
+			}
+		}
+	}
+	zr.Close()
+
+	unused := func(name string) bool { return strings.HasPrefix(name, "xl/media/") && !used[name] }
+	return rewriteParts(file, unused, func(string, []byte) []byte { return nil })
+}
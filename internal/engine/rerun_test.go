@@ -0,0 +1,112 @@
+package engine
+
+import (
+	"archive/zip"
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestProcessor_RunExistingPictures(t *testing.T) {
+	tests := []struct {
+		name         string
+		existing     string
+		wantPictures []int // Pictures in B1-B3 after the second run
+		wantInserted int
+		wantSkipped  int
+		wantReplaced int
+		wantMedia    int // Identical pictures share one media file
+	}{
+		{"add", ExistingAdd, []int{2, 2, 1}, 2, 0, 0, 3},
+		{"skip", ExistingSkip, []int{1, 1, 1}, 0, 2, 0, 3},
+		{"replace", ExistingReplace, []int{1, 1, 1}, 2, 0, 2, 3},
+		{"clear", ExistingClear, []int{1, 1, 0}, 2, 0, 3, 2},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			excelPath := filepath.Join(tempDir, "test.xlsx")
+			imageDir := filepath.Join(tempDir, "images")
+			_ = os.Mkdir(imageDir, 0755)
+			if err := createDummyExcel(excelPath, "Sheet1", "A", []string{"P001", "P002", "P003"}); err != nil {
+				t.Fatal(err)
+			}
+			_ = createDummyImage(filepath.Join(imageDir, "P001.png"), 100, 100)
+			_ = createDummyImage(filepath.Join(imageDir, "P002.png"), 100, 50)
+			other := filepath.Join(tempDir, "other.png")
+			_ = createDummyImage(other, 40, 40)
+
+			// The first run places P001 and P002; B3 gets a picture by hand
+			first := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 2, 100, 20)
+			if err := first.Run(context.Background()); err != nil {
+				t.Fatalf("first Run() error: %v", err)
+			}
+			f, err := excelize.OpenFile(first.OutputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := f.AddPicture("Sheet1", "B3", other, nil); err != nil {
+				t.Fatal(err)
+			}
+			if err := f.Save(); err != nil {
+				t.Fatal(err)
+			}
+			f.Close()
+
+			second := NewProcessor(first.OutputPath, imageDir, "A", "B", "Sheet1", 2, 100, 20)
+			second.Existing = tt.existing
+			if err := second.Run(context.Background()); err != nil {
+				t.Fatalf("second Run() error: %v", err)
+			}
+			totals := second.Report.Totals
+			if totals.Inserted != tt.wantInserted || totals.Skipped != tt.wantSkipped || totals.Replaced != tt.wantReplaced {
+				t.Errorf("inserted %d, skipped %d, replaced %d; want %d, %d and %d",
+					totals.Inserted, totals.Skipped, totals.Replaced, tt.wantInserted, tt.wantSkipped, tt.wantReplaced)
+			}
+			if second.ReplacedCount != tt.wantReplaced {
+				t.Errorf("ReplacedCount = %d, want %d", second.ReplacedCount, tt.wantReplaced)
+			}
+
+			out, err := excelize.OpenFile(second.OutputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer out.Close()
+			for i, want := range tt.wantPictures {
+				cell := "B" + string(rune('1'+i))
+				if pics, err := out.GetPictures("Sheet1", cell); err != nil || len(pics) != want {
+					t.Errorf("%s has %d pictures (%v), want %d", cell, len(pics), err, want)
+				}
+			}
+
+			// The picture added by hand keeps its content
+			if pics, _ := out.GetPictures("Sheet1", "B3"); tt.existing != ExistingClear && len(pics) == 1 {
+				if want, _ := os.ReadFile(other); !bytes.Equal(pics[0].File, want) {
+					t.Error("B3 shows a different picture")
+				}
+			}
+
+			// Deleted pictures leave no unused media behind
+			zr, err := zip.OpenReader(second.OutputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer zr.Close()
+			media := 0
+			for _, part := range zr.File {
+				if strings.HasPrefix(part.Name, "xl/media/") {
+					media++
+				}
+			}
+			if media != tt.wantMedia {
+				t.Errorf("workbook stores %d media files, want %d", media, tt.wantMedia)
+			}
+		})
+	}
+}
@@ -0,0 +1,196 @@
+package engine
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"math"
+
+	"golang.org/x/image/draw"
+)
+
+// Output formats for ResampleOptions.Format
+const (
+	FormatAuto = "auto" // JPEG stays JPEG, everything else becomes PNG (default)
+	FormatJPEG = "jpeg"
+	FormatPNG  = "png"
+)
+
+// DefaultJPEGQuality is used when ResampleOptions.Quality is 0
+const DefaultJPEGQuality = 85
+
+// ResampleOptions controls how images are shrunk before they are embedded
+type ResampleOptions struct {
+	KeepOriginal bool    // Embed the original files and only scale the pictures visually
+	DPIScale     float64 // Pixels per displayed pixel, e.g. 2 for sharper prints (default 1)
+	Quality      int     // JPEG quality 1-100 (default DefaultJPEGQuality)
+	Format       string  // FormatAuto, FormatJPEG or FormatPNG
+
+	// Framing makes products fill their cells consistently
+	Trim          bool   // Crop near-uniform borders
+	TrimTolerance *int   // Channel difference still counted as border, 0-255 (nil for DefaultTrimTolerance)
+	Pad           bool   // Pad to the aspect ratio of the cell
+	Background    string // Padding colour as #RRGGBB (default #FFFFFF)
+
+	// CMYK and wide-gamut (Adobe RGB, Display P3) images are converted to sRGB
+	KeepColors bool // Leave their colours alone; kept originals keep their profile
+}
+
+// validate checks the resample settings
+func (o ResampleOptions) validate() error {
+	switch o.Format {
+	case "", FormatAuto, FormatJPEG, FormatPNG:
+	default:
+		return fmt.Errorf("unknown output format '%s'", o.Format)
+	}
+	if o.Quality < 0 || o.Quality > 100 {
+		return fmt.Errorf("quality must be between 1 and 100, got %d", o.Quality)
+	}
+	if o.DPIScale < 0 {
+		return fmt.Errorf("DPI scale must not be negative, got %g", o.DPIScale)
+	}
+	if t := o.trimTolerance(); t < 0 || t > 255 {
+		return fmt.Errorf("trim tolerance must be between 0 and 255, got %d", t)
+	}
+	if _, err := o.background(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// trimTolerance returns the trim tolerance, DefaultTrimTolerance when unset
+func (o ResampleOptions) trimTolerance() int {
+	if o.TrimTolerance == nil {
+		return DefaultTrimTolerance
+	}
+	return *o.TrimTolerance
+}
+
+// dpiScale returns the DPI multiplier, 1 when unset
+func (o ResampleOptions) dpiScale() float64 {
+	if o.DPIScale <= 0 {
+		return 1
+	}
+	return o.DPIScale
+}
+
+// quality returns the JPEG quality, DefaultJPEGQuality when unset
+func (o ResampleOptions) quality() int {
+	if o.Quality == 0 {
+		return DefaultJPEGQuality
+	}
+	return o.Quality
+}
+
+// format returns the output format, FormatAuto when unset
+func (o ResampleOptions) format() string {
+	if o.Format == "" {
+		return FormatAuto
+	}
+	return o.Format
+}
+
+// resampled is an image re-encoded for embedding
+type resampled struct {
+	data          []byte
+	ext           string
+	width, height int
+}
+
+// resample decodes data, converts it to sRGB, applies its EXIF orientation,
+// optionally trims its borders and pads it to the box aspect ratio, shrinks it
+// to fit boxW x boxH display pixels times the DPI scale and re-encodes it.
+// Upright, unframed sRGB images that already fit are only re-encoded, and the
+// original bytes are kept when that does not save space and keepable is set.
+func (o ResampleOptions) resample(data []byte, ext string, boxW, boxH float64, keepable bool) (resampled, error) {
+	src, format, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return resampled{}, &ImageError{Reason: ReasonCorrupt, Err: err}
+	}
+	converted := false
+	if !o.KeepColors {
+		src, converted = toSRGB(src, data)
+	}
+	b := src.Bounds()
+	if o.Trim {
+		b = trimBounds(src, o.trimTolerance())
+	}
+	orientation := exifOrientation(data)
+	orig := resampled{data: data, ext: ext}
+	orig.width, orig.height = orientedSize(src.Bounds().Dx(), src.Bounds().Dy(), orientation)
+
+	// Displayed size of the content and of the (padded) canvas
+	w, h := orientedSize(b.Dx(), b.Dy(), orientation)
+	cw, ch := w, h
+	if o.Pad {
+		cw, ch = padToAspect(w, h, boxW/boxH)
+	}
+
+	scale := math.Min(boxW/float64(cw), boxH/float64(ch)) * o.dpiScale()
+	if scale < 1 {
+		w = max(1, int(math.Round(float64(w)*scale)))
+		h = max(1, int(math.Round(float64(h)*scale)))
+		cw = max(w, int(math.Round(float64(cw)*scale)))
+		ch = max(h, int(math.Round(float64(ch)*scale)))
+	}
+
+	out := o.format()
+	if out == FormatAuto {
+		out = FormatPNG
+		if format == "jpeg" {
+			out = FormatJPEG
+		}
+	}
+
+	// Draw in stored orientation, then rotate the smaller result. The content
+	// is centred on the canvas, so padding is symmetric in either orientation.
+	sw, sh := orientedSize(w, h, orientation)
+	scw, sch := orientedSize(cw, ch, orientation)
+	dst := image.NewRGBA(image.Rect(0, 0, scw, sch))
+	op := draw.Src
+	var background color.Color
+	switch {
+	case o.Pad:
+		background, _ = o.background()
+	case out == FormatJPEG:
+		background = color.White // JPEG has no transparency, flatten onto white
+	}
+	if background != nil {
+		draw.Draw(dst, dst.Bounds(), image.NewUniform(background), image.Point{}, draw.Src)
+		op = draw.Over
+	}
+	content := image.Rect(0, 0, sw, sh).Add(image.Pt((scw-sw)/2, (sch-sh)/2))
+	draw.CatmullRom.Scale(dst, content, src, b, op, nil)
+	oriented := orientRGBA(dst, orientation)
+
+	var buf bytes.Buffer
+	res := resampled{width: cw, height: ch}
+	if out == FormatJPEG {
+		err = jpeg.Encode(&buf, oriented, &jpeg.Options{Quality: o.quality()})
+		res.ext = ".jpg"
+	} else {
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, oriented)
+		res.ext = ".png"
+	}
+	if err != nil {
+		return resampled{}, fmt.Errorf("failed to encode image: %w", err)
+	}
+	res.data = buf.Bytes()
+
+	unchanged := scale >= 1 && orientation == 1 && b == src.Bounds() && cw == w && ch == h && !converted
+	if keepable && unchanged && len(res.data) >= len(data) {
+		return orig, nil
+	}
+	return res, nil
+}
+
+// background returns the padding colour, white by default
+func (o ResampleOptions) background() (color.RGBA, error) {
+	if o.Background == "" {
+		return color.RGBA{255, 255, 255, 255}, nil
+	}
+	return parseHexColor(o.Background)
+}
@@ -0,0 +1,146 @@
+package engine
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// encodeTestImage encodes a gradient image as PNG or JPEG
+func encodeTestImage(t *testing.T, format string, width, height int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	for x := 0; x < width; x++ {
+		for y := 0; y < height; y++ {
+			img.Set(x, y, color.RGBA{uint8(x), uint8(y), uint8(x + y), 255})
+		}
+	}
+	var buf bytes.Buffer
+	var err error
+	if format == FormatJPEG {
+		err = jpeg.Encode(&buf, img, &jpeg.Options{Quality: 95})
+	} else {
+		err = png.Encode(&buf, img)
+	}
+	if err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+func TestResampleOptions_Resample(t *testing.T) {
+	largePNG := encodeTestImage(t, FormatPNG, 1000, 500)
+	largeJPEG := encodeTestImage(t, FormatJPEG, 1000, 500)
+	smallJPEG := encodeTestImage(t, FormatJPEG, 40, 20)
+
+	tests := []struct {
+		name          string
+		opts          ResampleOptions
+		data          []byte
+		ext           string
+		wantExt       string
+		wantW, wantH  int
+		wantUnchanged bool
+	}{
+		{"png shrinks to box", ResampleOptions{}, largePNG, ".png", ".png", 130, 65, false},
+		{"dpi multiplier", ResampleOptions{DPIScale: 2}, largePNG, ".png", ".png", 260, 130, false},
+		{"jpeg stays jpeg", ResampleOptions{Quality: 70}, largeJPEG, ".jpeg", ".jpg", 130, 65, false},
+		{"forced jpeg", ResampleOptions{Format: FormatJPEG}, largePNG, ".png", ".jpg", 130, 65, false},
+		{"fitting image kept when not smaller", ResampleOptions{Quality: 100}, smallJPEG, ".jpg", ".jpg", 40, 20, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := tt.opts.resample(tt.data, tt.ext, 130, 123, true)
+			if err != nil {
+				t.Fatalf("resample() error: %v", err)
+			}
+			if got.ext != tt.wantExt || got.width != tt.wantW || got.height != tt.wantH {
+				t.Errorf("resample() = %s %dx%d, want %s %dx%d", got.ext, got.width, got.height, tt.wantExt, tt.wantW, tt.wantH)
+			}
+			if unchanged := bytes.Equal(got.data, tt.data); unchanged != tt.wantUnchanged {
+				t.Errorf("resample() kept original bytes = %v, want %v", unchanged, tt.wantUnchanged)
+			}
+			cfg, _, err := image.DecodeConfig(bytes.NewReader(got.data))
+			if err != nil || cfg.Width != tt.wantW || cfg.Height != tt.wantH {
+				t.Errorf("encoded image is %dx%d (err: %v)", cfg.Width, cfg.Height, err)
+			}
+		})
+	}
+
+	if _, err := (ResampleOptions{}).resample([]byte("broken"), ".png", 100, 100, true); err == nil {
+		t.Error("resample() of broken data succeeded")
+	}
+}
+
+func TestResampleOptions_Validate(t *testing.T) {
+	tests := []struct {
+		opts    ResampleOptions
+		wantErr bool
+	}{
+		{ResampleOptions{}, false},
+		{ResampleOptions{Format: FormatJPEG, Quality: 100, DPIScale: 2}, false},
+		{ResampleOptions{Format: "gif"}, true},
+		{ResampleOptions{Quality: 101}, true},
+		{ResampleOptions{DPIScale: -1}, true},
+	}
+	for _, tt := range tests {
+		if err := tt.opts.validate(); (err != nil) != tt.wantErr {
+			t.Errorf("validate(%+v) error = %v, wantErr %v", tt.opts, err, tt.wantErr)
+		}
+	}
+}
+
+func TestProcessor_RunResample(t *testing.T) {
+	for _, keep := range []bool{false, true} {
+		tempDir := t.TempDir()
+		excelPath := filepath.Join(tempDir, "test.xlsx")
+		imageDir := filepath.Join(tempDir, "images")
+		_ = os.Mkdir(imageDir, 0755)
+
+		if err := createDummyExcel(excelPath, "Sheet1", "A", []string{"P001"}); err != nil {
+			t.Fatal(err)
+		}
+		original := encodeTestImage(t, FormatPNG, 1000, 500)
+		_ = os.WriteFile(filepath.Join(imageDir, "P001.png"), original, 0644)
+
+		p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 1, 100, 20)
+		p.Resample.KeepOriginal = keep
+		if err := p.Run(context.Background()); err != nil {
+			t.Fatalf("Run() error: %v", err)
+		}
+
+		f, err := excelize.OpenFile(p.OutputPath)
+		if err != nil {
+			t.Fatal(err)
+		}
+		pics, err := f.GetPictures("Sheet1", "B1")
+		f.Close()
+		if err != nil || len(pics) != 1 {
+			t.Fatalf("expected one picture, got %d (err: %v)", len(pics), err)
+		}
+		cfg, _, _ := image.DecodeConfig(bytes.NewReader(pics[0].File))
+
+		totals := p.Report.Totals
+		if keep {
+			if !bytes.Equal(pics[0].File, original) || totals.SavedBytes != 0 {
+				t.Errorf("KeepOriginal embedded %dx%d, saved %d bytes", cfg.Width, cfg.Height, totals.SavedBytes)
+			}
+			continue
+		}
+		if cfg.Width != 130 || cfg.Height != 65 {
+			t.Errorf("embedded picture is %dx%d, want 130x65", cfg.Width, cfg.Height)
+		}
+		if totals.OriginalBytes != int64(len(original)) || totals.EmbeddedBytes != int64(len(pics[0].File)) || totals.SavedBytes <= 0 {
+			t.Errorf("unexpected byte totals: %+v", totals)
+		}
+	}
+}
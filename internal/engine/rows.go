@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"image"
+	"math"
+	"os"
+)
+
+// Row height modes for RowOptions.Mode
+const (
+	RowHeightFixed = "fixed" // Matched rows get Processor.RowHeight (default)
+	RowHeightAuto  = "auto"  // Matched rows fit the aspect ratio of their pictures at the column width
+)
+
+// Policies for rows whose code has no image, RowOptions.Unmatched
+const (
+	UnmatchedKeep   = "keep"   // Leave their height as it is (default)
+	UnmatchedResize = "resize" // Give them Processor.RowHeight like the matched rows
+)
+
+// Bounds of automatic row heights in points
+const (
+	DefaultMinRowHeight = 15  // Excel's default row height
+	DefaultMaxRowHeight = 409 // Excel's maximum row height
+)
+
+// RowOptions controls the height of the rows pictures are placed on
+type RowOptions struct {
+	Mode      string   // RowHeightFixed or RowHeightAuto
+	MinHeight *float64 // Lowest automatic height in points (nil for DefaultMinRowHeight)
+	MaxHeight *float64 // Highest automatic height in points (nil for DefaultMaxRowHeight)
+	Unmatched string   // UnmatchedKeep or UnmatchedResize
+}
+
+// validate checks the row options
+func (o RowOptions) validate() error {
+	switch o.Mode {
+	case "", RowHeightFixed, RowHeightAuto:
+	default:
+		return errors.New("invalid row height mode '" + o.Mode + "'")
+	}
+	switch o.Unmatched {
+	case "", UnmatchedKeep, UnmatchedResize:
+	default:
+		return errors.New("invalid unmatched row policy '" + o.Unmatched + "'")
+	}
+	lo, hi := o.bounds()
+	if lo < 0 || hi <= 0 || hi > DefaultMaxRowHeight || lo > hi {
+		return fmt.Errorf("row height bounds must satisfy 0 <= min <= max <= %d and max > 0, got %g and %g", DefaultMaxRowHeight, lo, hi)
+	}
+	return nil
+}
+
+// bounds returns the minimum and maximum automatic row height
+func (o RowOptions) bounds() (float64, float64) {
+	lo, hi := float64(DefaultMinRowHeight), float64(DefaultMaxRowHeight)
+	if o.MinHeight != nil {
+		lo = *o.MinHeight
+	}
+	if o.MaxHeight != nil {
+		hi = *o.MaxHeight
+	}
+	return lo, hi
+}
+
+// measureRows sizes every matched row to the tallest picture placed on it
+// when automatic row heights are enabled. Only image headers are read
+// unless the pictures are trimmed.
+func (p *Processor) measureRows(jobs []Job) {
+	p.rowHeights = make(map[RowRef]float64)
+	if p.Rows.Mode != RowHeightAuto {
+		return
+	}
+	for _, job := range jobs {
+		width, height, err := p.contentSize(job.ImagePath)
+		if err != nil || width == 0 || height == 0 {
+			continue // The worker reports the error
+		}
+		h := p.fitRowHeight(job, width, height)
+		for _, ref := range job.Rows {
+			p.rowHeights[ref] = max(p.rowHeights[ref], h)
+		}
+	}
+}
+
+// contentSize returns the displayed pixel size of the picture at path. With
+// Resample.Trim this is the size left after trimming, which takes a full
+// decode. Padding needs no allowance: it pads to the cell the row is sized
+// for, so the content already fills it.
+func (p *Processor) contentSize(path string) (int, int, error) {
+	cfg, err := decodeConfigFile(path)
+	if err != nil || !p.Resample.Trim {
+		return cfg.Width, cfg.Height, err
+	}
+	if cfg.Width*cfg.Height > p.maxPixels() {
+		return 0, 0, fmt.Errorf("%dx%d exceeds the pixel limit", cfg.Width, cfg.Height)
+	}
+	if err := p.checkFileSize(path); err != nil {
+		return 0, 0, err
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return 0, 0, err
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return 0, 0, err
+	}
+	if !p.Resample.KeepColors {
+		src, _ = toSRGB(src, data)
+	}
+	b := trimBounds(src, p.Resample.trimTolerance())
+	w, h := orientedSize(b.Dx(), b.Dy(), exifOrientation(data))
+	return w, h, nil
+}
+
+// fitRowHeight returns the row height in points at which a width x height
+// picture of job fills the width of its cell, within the configured bounds
+func (p *Processor) fitRowHeight(job Job, width, height int) float64 {
+	marginX, marginY := p.Placement.margins()
+	boxW := max(1, p.cellWidth()-float64(2*marginX))
+	slots := 1
+	if p.Variants.Layout == LayoutStack && job.SlotCount > 1 {
+		slots = job.SlotCount
+	}
+	slotH := boxW*float64(height)/float64(width) + float64(marginY)
+	cellH := math.Ceil(float64(slots)*slotH + float64(marginY))
+	lo, hi := p.Rows.bounds()
+	return math.Max(lo, math.Min(hi, cellH*72/96))
+}
+
+// rowHeight returns the height in points of a matched row
+func (p *Processor) rowHeight(ref RowRef) float64 {
+	if h, ok := p.rowHeights[ref]; ok {
+		return h
+	}
+	return p.RowHeight
+}
+
+// jobRowHeight returns the height of the tallest row job is placed on
+func (p *Processor) jobRowHeight(job Job) float64 {
+	h := 0.0
+	for _, ref := range job.Rows {
+		h = max(h, p.rowHeight(ref))
+	}
+	if h == 0 {
+		return p.RowHeight
+	}
+	return h
+}
@@ -0,0 +1,169 @@
+package engine
+
+import (
+	"context"
+	"image"
+	"image/draw"
+	"image/png"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestProcessor_FitRowHeight(t *testing.T) {
+	// Column 20 = 140px with Calibri 11, so pictures are 130px wide
+	tests := []struct {
+		name          string
+		rows          RowOptions
+		stack         bool
+		width, height int
+		want          float64
+	}{
+		{"wide", RowOptions{}, false, 300, 100, 40.5},
+		{"tall", RowOptions{}, false, 100, 300, 300},
+		{"clamped to max", RowOptions{}, false, 100, 1000, DefaultMaxRowHeight},
+		{"clamped to min", RowOptions{}, false, 1000, 10, DefaultMinRowHeight},
+		{"custom bounds", RowOptions{MinHeight: ptr(50.0), MaxHeight: ptr(200.0)}, false, 100, 300, 200},
+		{"zero min", RowOptions{MinHeight: ptr(0.0)}, false, 1000, 10, 9},
+		{"stacked", RowOptions{}, true, 300, 100, 76.5},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := NewProcessor("", "", "A", "B", "Sheet1", 1, 100, 20)
+			p.Rows = tt.rows
+			job := Job{SlotCount: 1}
+			if tt.stack {
+				p.Variants.Layout = LayoutStack
+				job.SlotCount = 2
+			}
+			if got := p.fitRowHeight(job, tt.width, tt.height); got != tt.want {
+				t.Errorf("fitRowHeight() = %g, want %g", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRowOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    RowOptions
+		wantErr bool
+	}{
+		{"defaults", RowOptions{}, false},
+		{"auto", RowOptions{Mode: RowHeightAuto, Unmatched: UnmatchedResize}, false},
+		{"unknown mode", RowOptions{Mode: "tall"}, true},
+		{"unknown policy", RowOptions{Unmatched: "hide"}, true},
+		{"zero min", RowOptions{MinHeight: ptr(0.0)}, false},
+		{"min above max", RowOptions{MinHeight: ptr(100.0), MaxHeight: ptr(50.0)}, true},
+		{"max above limit", RowOptions{MaxHeight: ptr(500.0)}, true},
+		{"zero max", RowOptions{MinHeight: ptr(0.0), MaxHeight: ptr(0.0)}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessor_RunRowHeights(t *testing.T) {
+	tests := []struct {
+		name string
+		rows RowOptions
+		want []float64 // Rows 1-3: wide picture, tall picture, missing image
+	}{
+		{"fixed", RowOptions{}, []float64{100, 100, 15}},
+		{"fixed resizing unmatched", RowOptions{Unmatched: UnmatchedResize}, []float64{100, 100, 100}},
+		{"auto", RowOptions{Mode: RowHeightAuto}, []float64{40.5, 300, 15}},
+		{"auto resizing unmatched", RowOptions{Mode: RowHeightAuto, Unmatched: UnmatchedResize}, []float64{40.5, 300, 100}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			excelPath := filepath.Join(tempDir, "test.xlsx")
+			imageDir := filepath.Join(tempDir, "images")
+			_ = os.Mkdir(imageDir, 0755)
+			if err := createDummyExcel(excelPath, "Sheet1", "A", []string{"P001", "P002", "P003"}); err != nil {
+				t.Fatal(err)
+			}
+			_ = createDummyImage(filepath.Join(imageDir, "P001.png"), 300, 100)
+			_ = createDummyImage(filepath.Join(imageDir, "P002.png"), 100, 300)
+
+			p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 2, 100, 20)
+			p.Rows = tt.rows
+			if err := p.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+
+			f, err := excelize.OpenFile(p.OutputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			for i, want := range tt.want {
+				if got, _ := f.GetRowHeight("Sheet1", i+1); got != want {
+					t.Errorf("row %d height = %g, want %g", i+1, got, want)
+				}
+			}
+			for _, row := range p.Report.Rows {
+				if row.Status == StatusInserted && row.ScaledWidth != 130 && tt.rows.Mode == RowHeightAuto {
+					t.Errorf("%s is %dpx wide, want the full 130px", row.Code, row.ScaledWidth)
+				}
+			}
+		})
+	}
+}
+
+func TestProcessor_RunRowHeightsTrimmed(t *testing.T) {
+	tests := []struct {
+		name     string
+		resample ResampleOptions
+		want     float64
+		wantH    int // Scaled height of the 130px wide picture
+	}{
+		{"untrimmed", ResampleOptions{}, 40.5, 43},
+		{"trimmed", ResampleOptions{Trim: true}, 105, 130},
+		{"trimmed and padded", ResampleOptions{Trim: true, Pad: true}, 105, 130},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			excelPath := filepath.Join(tempDir, "test.xlsx")
+			imageDir := filepath.Join(tempDir, "images")
+			_ = os.Mkdir(imageDir, 0755)
+			if err := createDummyExcel(excelPath, "Sheet1", "A", []string{"P001"}); err != nil {
+				t.Fatal(err)
+			}
+			// A 200x200 product on a 600x200 white background
+			img := image.NewRGBA(image.Rect(0, 0, 600, 200))
+			draw.Draw(img, img.Bounds(), image.White, image.Point{}, draw.Src)
+			draw.Draw(img, image.Rect(200, 0, 400, 200), image.Black, image.Point{}, draw.Src)
+			out, _ := os.Create(filepath.Join(imageDir, "P001.png"))
+			_ = png.Encode(out, img)
+			out.Close()
+
+			p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 1, 100, 20)
+			p.Rows.Mode = RowHeightAuto
+			p.Resample = tt.resample
+			if err := p.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+
+			f, err := excelize.OpenFile(p.OutputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+			if got, _ := f.GetRowHeight("Sheet1", 1); got != tt.want {
+				t.Errorf("row height = %g, want %g", got, tt.want)
+			}
+			row := p.Report.Rows[0]
+			if row.ScaledWidth != 130 || row.ScaledHeight != tt.wantH {
+				t.Errorf("picture is %dx%d, want 130x%d", row.ScaledWidth, row.ScaledHeight, tt.wantH)
+			}
+		})
+	}
+}
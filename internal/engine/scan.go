@@ -0,0 +1,307 @@
+package engine
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Symlink policies for ScanOptions.Symlinks
+const (
+	SymlinkFiles  = "files"  // Follow links to files, ignore links to directories (default)
+	SymlinkSkip   = "skip"   // Ignore every symlink
+	SymlinkFollow = "follow" // Follow links to files and directories
+)
+
+// supportedExts lists the image extensions picked up from the image directory
+var supportedExts = map[string]bool{
+	".jpg":  true,
+	".jpeg": true,
+	".png":  true,
+	".gif":  true,
+	".webp": true,
+	".bmp":  true,
+	".tif":  true,
+	".tiff": true,
+}
+
+// ScanOptions controls how the image directory is scanned
+type ScanOptions struct {
+	Recursive bool     // Descend into subfolders
+	MaxDepth  int      // Maximum subfolder depth when recursive, 0 = unlimited
+	Include   []string // Glob patterns a file must match (relative path or name), empty = all
+	Exclude   []string // Glob patterns for files and folders to skip (relative path or name)
+	Symlinks  string   // SymlinkFiles, SymlinkSkip or SymlinkFollow
+	// Folders listed here win over all others when the same filename exists in
+	// several folders, in list order. Remaining folders are ranked by depth
+	// (shallowest first), then alphabetically by path.
+	FolderPriority []string
+}
+
+// Collision records an image name found in more than one folder
+type Collision struct {
+	Name    string   `json:"name"`
+	Chosen  string   `json:"chosen"`
+	Ignored []string `json:"ignored"`
+}
+
+// imageFile is a supported image found while scanning
+type imageFile struct {
+	Rel     string // Path relative to the image directory
+	Stem    string // Filename without extension
+	Base    string // Stem without the variant suffix
+	Variant int    // Variant number, 0 for the base image
+	Depth   int    // Number of folders below the image directory
+}
+
+// imageIndex resolves product codes to the images inserted for them
+type imageIndex struct {
+	m      *matcher
+	groups [][]imageFile
+}
+
+// lookup returns the images for code, in placement order, and the match rule
+func (idx *imageIndex) lookup(code string) ([]imageFile, string, bool) {
+	group, rule, ok := idx.m.lookup(code)
+	if !ok {
+		return nil, "", false
+	}
+	return idx.groups[group], rule, true
+}
+
+// indexImages scans the image directory, groups variants of the same code and
+// indexes every group by its base name according to the match options. Groups
+// are indexed in precedence order so the first one wins when normalized names
+// collide.
+func (p *Processor) indexImages() (*imageIndex, error) {
+	m, err := newMatcher(p.Match)
+	if err != nil {
+		return nil, fmt.Errorf("invalid match options: %w", err)
+	}
+
+	files, err := p.scanImages()
+	if err != nil {
+		return nil, err
+	}
+	for i := range files {
+		files[i].Base, files[i].Variant = p.Variants.split(files[i].Stem)
+	}
+
+	idx := &imageIndex{m: m}
+	idx.groups = p.groupImages(files)
+	if p.ConflictPolicy == ConflictFail && len(p.Conflicts) > 0 {
+		return nil, conflictError(p.Conflicts)
+	}
+	for i, group := range idx.groups {
+		m.add(group[0].Base, i)
+	}
+
+	// A variant file stays reachable by its full stem, so codes that merely
+	// look like a variant (e.g. "ABC_2") still find their own image.
+	for _, f := range files {
+		if f.Stem != f.Base {
+			m.add(f.Stem, len(idx.groups))
+			idx.groups = append(idx.groups, []imageFile{f})
+		}
+	}
+	return idx, nil
+}
+
+// validate checks the symlink policy and the include and exclude patterns
+func (o ScanOptions) validate() error {
+	switch o.Symlinks {
+	case "", SymlinkFiles, SymlinkSkip, SymlinkFollow:
+	default:
+		return fmt.Errorf("invalid symlink policy '%s'", o.Symlinks)
+	}
+	for _, pattern := range append(append([]string{}, o.Include...), o.Exclude...) {
+		if _, err := path.Match(filepath.ToSlash(pattern), ""); err != nil {
+			return fmt.Errorf("invalid glob pattern '%s': %w", pattern, err)
+		}
+	}
+	return nil
+}
+
+// scanImages lists the supported images below ImageDir, sorted by precedence
+func (p *Processor) scanImages() ([]imageFile, error) {
+	var files []imageFile
+	visited := make(map[string]bool)
+	if err := p.walkImages(p.ImageDir, "", 0, visited, &files); err != nil {
+		return nil, fmt.Errorf("failed to read image directory: %w", err)
+	}
+
+	priority := make(map[string]int, len(p.Scan.FolderPriority))
+	for i, folder := range p.Scan.FolderPriority {
+		priority[filepath.Clean(folder)] = i + 1
+	}
+	rank := func(f imageFile) int {
+		if r, ok := priority[filepath.Dir(f.Rel)]; ok {
+			return r
+		}
+		return len(priority) + 1
+	}
+
+	sort.SliceStable(files, func(i, j int) bool {
+		ri, rj := rank(files[i]), rank(files[j])
+		if ri != rj {
+			return ri < rj
+		}
+		if files[i].Depth != files[j].Depth {
+			return files[i].Depth < files[j].Depth
+		}
+		return files[i].Rel < files[j].Rel
+	})
+	return files, nil
+}
+
+// walkImages collects images in dir and, when recursive, its subfolders
+func (p *Processor) walkImages(dir, rel string, depth int, visited map[string]bool, out *[]imageFile) error {
+	if p.Scan.Symlinks == SymlinkFollow {
+		// Guard against symlink loops
+		resolved, err := filepath.EvalSymlinks(dir)
+		if err != nil {
+			return err
+		}
+		if visited[resolved] {
+			return nil
+		}
+		visited[resolved] = true
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range entries {
+		name := entry.Name()
+		entryRel := filepath.Join(rel, name)
+		if matchesAny(p.Scan.Exclude, entryRel) {
+			continue
+		}
+
+		isDir := entry.IsDir()
+		if entry.Type()&os.ModeSymlink != 0 {
+			if p.Scan.Symlinks == SymlinkSkip {
+				continue
+			}
+			info, err := os.Stat(filepath.Join(dir, name))
+			if err != nil {
+				continue // Dangling link
+			}
+			isDir = info.IsDir()
+			if isDir && p.Scan.Symlinks != SymlinkFollow {
+				continue
+			}
+		}
+
+		if isDir {
+			if !p.Scan.Recursive || (p.Scan.MaxDepth > 0 && depth >= p.Scan.MaxDepth) {
+				continue
+			}
+			if err := p.walkImages(filepath.Join(dir, name), entryRel, depth+1, visited, out); err != nil {
+				return err
+			}
+			continue
+		}
+
+		ext := filepath.Ext(name)
+		if !supportedExts[strings.ToLower(ext)] {
+			continue
+		}
+		if len(p.Scan.Include) > 0 && !matchesAny(p.Scan.Include, entryRel) {
+			continue
+		}
+		*out = append(*out, imageFile{
+			Rel:   entryRel,
+			Stem:  strings.TrimSuffix(name, ext),
+			Depth: depth,
+		})
+	}
+	return nil
+}
+
+// matchesAny reports whether rel or its base name matches one of the glob patterns
+func matchesAny(patterns []string, rel string) bool {
+	slashRel := filepath.ToSlash(rel)
+	base := filepath.Base(rel)
+	for _, pattern := range patterns {
+		pattern = filepath.ToSlash(pattern)
+		if ok, _ := path.Match(pattern, slashRel); ok {
+			return true
+		}
+		if ok, _ := path.Match(pattern, base); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// groupImages groups files by base name and records collisions and
+// conflicts. files must be sorted by precedence: the folder of the first file
+// of a base name wins and files with the same base in other folders are
+// reported as collisions. Within the winning folder, files competing for the
+// same variant number are settled by the conflict policy.
+func (p *Processor) groupImages(files []imageFile) [][]imageFile {
+	byBase := make(map[string][]imageFile)
+	var bases []string
+	for _, f := range files {
+		if _, ok := byBase[f.Base]; !ok {
+			bases = append(bases, f.Base)
+		}
+		byBase[f.Base] = append(byBase[f.Base], f)
+	}
+
+	p.Collisions = nil
+	p.Conflicts = nil
+	groups := make([][]imageFile, 0, len(bases))
+	for _, base := range bases {
+		all := byBase[base]
+		chosenDir := filepath.Dir(all[0].Rel)
+		byVariant := make(map[int][]imageFile)
+		var variants []int
+		var ignored []string
+		for _, f := range all {
+			if filepath.Dir(f.Rel) != chosenDir {
+				ignored = append(ignored, f.Rel)
+				continue
+			}
+			if _, ok := byVariant[f.Variant]; !ok {
+				variants = append(variants, f.Variant)
+			}
+			byVariant[f.Variant] = append(byVariant[f.Variant], f)
+		}
+
+		var group []imageFile
+		for _, variant := range variants {
+			candidates := byVariant[variant]
+			if len(candidates) == 1 {
+				group = append(group, candidates[0])
+				continue
+			}
+			chosen := p.resolveConflict(candidates)
+			conflict := Conflict{Name: chosen.Stem, Chosen: chosen.Rel, Policy: p.ConflictPolicy}
+			if conflict.Policy == "" {
+				conflict.Policy = ConflictExtension
+			}
+			for _, f := range candidates {
+				conflict.Candidates = append(conflict.Candidates, f.Rel)
+			}
+			p.Conflicts = append(p.Conflicts, conflict)
+			group = append(group, chosen)
+		}
+
+		group = p.Variants.arrange(group)
+		groups = append(groups, group)
+		if len(ignored) > 0 {
+			p.Collisions = append(p.Collisions, Collision{Name: base, Chosen: group[0].Rel, Ignored: ignored})
+		}
+	}
+
+	sort.Slice(p.Collisions, func(i, j int) bool { return p.Collisions[i].Name < p.Collisions[j].Name })
+	sort.Slice(p.Conflicts, func(i, j int) bool { return p.Conflicts[i].Chosen < p.Conflicts[j].Chosen })
+	return groups
+}
@@ -0,0 +1,174 @@
+package engine
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// createImageTree creates empty image files at the given relative paths
+func createImageTree(t *testing.T, root string, paths []string) {
+	t.Helper()
+	for _, rel := range paths {
+		full := filepath.Join(root, filepath.FromSlash(rel))
+		if err := os.MkdirAll(filepath.Dir(full), 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(full, nil, 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+}
+
+func TestProcessor_ScanImages(t *testing.T) {
+	root := t.TempDir()
+	createImageTree(t, root, []string{
+		"P001.jpg",
+		"notes.txt",
+		"brandA/P002.png",
+		"brandA/shoes/P003.png",
+		"brandA/old/P004.png",
+		"brandB/P002.jpg",
+	})
+
+	tests := []struct {
+		name string
+		scan ScanOptions
+		want []string
+	}{
+		{"flat", ScanOptions{}, []string{"P001.jpg"}},
+		{"recursive", ScanOptions{Recursive: true}, []string{
+			"P001.jpg", "brandA/P002.png", "brandB/P002.jpg", "brandA/old/P004.png", "brandA/shoes/P003.png",
+		}},
+		{"max depth", ScanOptions{Recursive: true, MaxDepth: 1}, []string{
+			"P001.jpg", "brandA/P002.png", "brandB/P002.jpg",
+		}},
+		{"exclude folder", ScanOptions{Recursive: true, Exclude: []string{"old"}}, []string{
+			"P001.jpg", "brandA/P002.png", "brandB/P002.jpg", "brandA/shoes/P003.png",
+		}},
+		{"include pattern", ScanOptions{Recursive: true, Include: []string{"*.png"}}, []string{
+			"brandA/P002.png", "brandA/old/P004.png", "brandA/shoes/P003.png",
+		}},
+		{"include path pattern", ScanOptions{Recursive: true, Include: []string{"brandA/*/*"}}, []string{
+			"brandA/old/P004.png", "brandA/shoes/P003.png",
+		}},
+		{"folder priority", ScanOptions{Recursive: true, MaxDepth: 1, FolderPriority: []string{"brandB"}}, []string{
+			"brandB/P002.jpg", "P001.jpg", "brandA/P002.png",
+		}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := &Processor{ImageDir: root, Scan: tt.scan}
+			files, err := p.scanImages()
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make([]string, len(files))
+			for i, f := range files {
+				got[i] = filepath.ToSlash(f.Rel)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scanImages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestScanOptions_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ScanOptions
+		wantErr bool
+	}{
+		{"defaults", ScanOptions{}, false},
+		{"globs", ScanOptions{Include: []string{"*.png", "spring/*"}, Exclude: []string{"old"}, Symlinks: SymlinkFollow}, false},
+		{"unknown symlink policy", ScanOptions{Symlinks: "sometimes"}, true},
+		{"bad include", ScanOptions{Include: []string{"[a"}}, true},
+		{"bad exclude", ScanOptions{Exclude: []string{"old/[a"}}, true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.opts.validate(); (err != nil) != tt.wantErr {
+				t.Errorf("validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestProcessor_ScanImagesSymlinks(t *testing.T) {
+	root := t.TempDir()
+	outside := t.TempDir()
+	createImageTree(t, outside, []string{"linked/P010.png", "P011.png"})
+	createImageTree(t, root, []string{"P001.png"})
+	if err := os.Symlink(filepath.Join(outside, "linked"), filepath.Join(root, "linked")); err != nil {
+		t.Skipf("symlinks not supported: %v", err)
+	}
+	_ = os.Symlink(filepath.Join(outside, "P011.png"), filepath.Join(root, "P011.png"))
+	_ = os.Symlink(root, filepath.Join(root, "loop"))
+
+	tests := []struct {
+		policy string
+		want   []string
+	}{
+		{SymlinkSkip, []string{"P001.png"}},
+		{SymlinkFiles, []string{"P001.png", "P011.png"}},
+		{SymlinkFollow, []string{"P001.png", "P011.png", "linked/P010.png"}},
+	}
+	for _, tt := range tests {
+		t.Run(tt.policy, func(t *testing.T) {
+			p := &Processor{ImageDir: root, Scan: ScanOptions{Recursive: true, Symlinks: tt.policy}}
+			files, err := p.scanImages()
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, f := range files {
+				got = append(got, filepath.ToSlash(f.Rel))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("scanImages() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestGroupImages(t *testing.T) {
+	files := []imageFile{
+		{Rel: "P001.jpg", Stem: "P001", Base: "P001"},
+		{Rel: filepath.Join("a", "P002.png"), Stem: "P002", Base: "P002", Depth: 1},
+		{Rel: filepath.Join("a", "P002.jpg"), Stem: "P002", Base: "P002", Depth: 1},
+		{Rel: filepath.Join("a", "P002_2.jpg"), Stem: "P002_2", Base: "P002", Variant: 2, Depth: 1},
+		{Rel: filepath.Join("b", "P002.png"), Stem: "P002", Base: "P002", Depth: 1},
+		{Rel: filepath.Join("b", "P001.png"), Stem: "P001", Base: "P001", Depth: 1},
+	}
+	p := &Processor{Variants: VariantOptions{Suffix: "_{n}"}}
+	groups := p.groupImages(files)
+	collisions := p.Collisions
+
+	wantGroups := [][]string{
+		{"P001.jpg"},
+		{filepath.Join("a", "P002.png"), filepath.Join("a", "P002_2.jpg")},
+	}
+	if len(groups) != len(wantGroups) {
+		t.Fatalf("groupImages() returned %d groups, want %d", len(groups), len(wantGroups))
+	}
+	for i, group := range groups {
+		var got []string
+		for _, f := range group {
+			got = append(got, f.Rel)
+		}
+		if !reflect.DeepEqual(got, wantGroups[i]) {
+			t.Errorf("group %d = %v, want %v", i, got, wantGroups[i])
+		}
+	}
+
+	wantCollisions := []Collision{
+		{Name: "P001", Chosen: "P001.jpg", Ignored: []string{filepath.Join("b", "P001.png")}},
+		{Name: "P002", Chosen: filepath.Join("a", "P002.png"), Ignored: []string{filepath.Join("b", "P002.png")}},
+	}
+	if !reflect.DeepEqual(collisions, wantCollisions) {
+		t.Errorf("groupImages() collisions = %+v, want %+v", collisions, wantCollisions)
+	}
+}
@@ -0,0 +1,319 @@
+package engine
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"log"
+	"path"
+	"sort"
+	"strings"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// SheetTarget selects a sheet to process. Empty column settings fall back to
+// the Processor's CodeCol/CodeHeader and ImageCol/ImageHeader.
+type SheetTarget struct {
+	Name        string `json:"name"`
+	CodeCol     string `json:"codeCol"`
+	ImageCol    string `json:"imageCol"`
+	CodeHeader  string `json:"codeHeader"`
+	ImageHeader string `json:"imageHeader"`
+}
+
+// SheetStat reports the resolved columns and counts of one processed sheet
+type SheetStat struct {
+	Name      string `json:"name"`
+	CodeCol   string `json:"codeCol"`
+	ImageCol  string `json:"imageCol"`
+	Rows      int    `json:"rows"`      // Rows holding a product code
+	Processed int    `json:"processed"` // Pictures inserted
+	Missing   int    `json:"missing"`   // Distinct codes without an image
+}
+
+// RowRef identifies a data row of a sheet
+type RowRef struct {
+	Sheet string `json:"sheet"`
+	Row   int    `json:"row"`
+}
+
+// sheetInfo is the resolved layout of a target sheet
+type sheetInfo struct {
+	headerColumns
+	Name        string
+	ImageHeader string
+	HeaderRow   int
+	lastCol     int // Widest row read, in columns
+	stat        *SheetStat
+}
+
+// sheetTargets lists the sheets of this run with their column settings.
+// Without Sheets or AllSheets only SheetName (or the first sheet) is used.
+// With AllSheets, entries of Sheets act as per-sheet overrides.
+func (p *Processor) sheetTargets() ([]SheetTarget, error) {
+	overrides := make(map[string]SheetTarget, len(p.Sheets))
+	var names []string
+	for _, t := range p.Sheets {
+		overrides[t.Name] = t
+		names = append(names, t.Name)
+	}
+
+	switch {
+	case p.AllSheets:
+		names = p.f.GetSheetList()
+	case len(names) == 0:
+		name := p.SheetName
+		if name == "" {
+			name = p.f.GetSheetName(0)
+		}
+		names = []string{name}
+	}
+
+	types, err := sheetTypes(p.ExcelPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read sheet types: %w", err)
+	}
+	targets := make([]SheetTarget, 0, len(names))
+	seen := make(map[string]bool, len(names))
+	for _, name := range names {
+		if seen[name] {
+			continue
+		}
+		seen[name] = true
+		if idx, err := p.f.GetSheetIndex(name); err != nil || idx == -1 {
+			return nil, fmt.Errorf("sheet '%s' not found", name)
+		}
+		switch typ := types[name]; {
+		case typ == sheetTypeWorksheet:
+		case otherSheetTypes[typ]:
+			if p.AllSheets {
+				continue // Chart sheets have no cells to fill
+			}
+			return nil, fmt.Errorf("sheet '%s' is a %s, not a worksheet", name, typ)
+		default:
+			return nil, fmt.Errorf("sheet '%s' has an unknown type '%s'", name, typ)
+		}
+
+		t, ok := overrides[name]
+		if !ok {
+			t = SheetTarget{Name: name}
+		}
+		if t.CodeCol == "" && t.CodeHeader == "" {
+			t.CodeCol, t.CodeHeader = p.CodeCol, p.CodeHeader
+		}
+		if t.ImageCol == "" && t.ImageHeader == "" {
+			t.ImageCol, t.ImageHeader = p.ImageCol, p.ImageHeader
+		}
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+// Sheet types, the last segment of the relationship type of a sheet part
+const sheetTypeWorksheet = "worksheet"
+
+// otherSheetTypes are listed with the worksheets but have no cells to fill
+var otherSheetTypes = map[string]bool{
+	"chartsheet":       true,
+	"dialogsheet":      true,
+	"xlMacrosheet":     true,
+	"xlIntlMacrosheet": true,
+}
+
+// sheetTypes maps every sheet of the workbook at file to its type, read
+// from the relationship between the workbook and the sheet part
+func sheetTypes(file string) (map[string]string, error) {
+	zr, err := zip.OpenReader(file)
+	if err != nil {
+		return nil, err
+	}
+	defer zr.Close()
+	parts := make(map[string]*zip.File, len(zr.File))
+	for _, f := range zr.File {
+		parts[f.Name] = f
+	}
+	type relationships struct {
+		Relationships []struct {
+			ID     string `xml:"Id,attr"`
+			Type   string `xml:",attr"`
+			Target string `xml:",attr"`
+		} `xml:"Relationship"`
+	}
+	read := func(name string, v any) error {
+		f, ok := parts[name]
+		if !ok {
+			return fmt.Errorf("missing part %s", name)
+		}
+		rc, err := f.Open()
+		if err != nil {
+			return err
+		}
+		defer rc.Close()
+		if err := xml.NewDecoder(io.LimitReader(rc, 64<<20)).Decode(v); err != nil {
+			return fmt.Errorf("failed to read %s: %w", name, err)
+		}
+		return nil
+	}
+
+	var root relationships
+	if err := read("_rels/.rels", &root); err != nil {
+		return nil, err
+	}
+	workbook := ""
+	for _, rel := range root.Relationships {
+		if path.Base(rel.Type) == "officeDocument" {
+			workbook = strings.TrimPrefix(rel.Target, "/")
+		}
+	}
+	var wb struct {
+		Sheets []struct {
+			Name string `xml:"name,attr"`
+			ID   string `xml:"id,attr"` // r:id
+		} `xml:"sheets>sheet"`
+	}
+	if err := read(workbook, &wb); err != nil {
+		return nil, err
+	}
+	var rels relationships
+	if err := read(path.Join(path.Dir(workbook), "_rels", path.Base(workbook)+".rels"), &rels); err != nil {
+		return nil, err
+	}
+	types := make(map[string]string, len(rels.Relationships))
+	for _, rel := range rels.Relationships {
+		types[rel.ID] = path.Base(rel.Type)
+	}
+	sheets := make(map[string]string, len(wb.Sheets))
+	for _, s := range wb.Sheets {
+		sheets[s.Name] = types[s.ID]
+	}
+	return sheets, nil
+}
+
+// mapSheets resolves the columns of every target sheet and reads their
+// product codes into productMap
+func (p *Processor) mapSheets(ctx context.Context) error {
+	targets, err := p.sheetTargets()
+	if err != nil {
+		return err
+	}
+
+	p.sheets = make(map[string]*sheetInfo, len(targets))
+	p.rowPaths = make(map[RowRef]string)
+	p.SheetStats = make([]SheetStat, len(targets))
+	p.Duplicates = nil
+	for i, t := range targets {
+		info := &sheetInfo{Name: t.Name, ImageHeader: t.ImageHeader, stat: &p.SheetStats[i]}
+		info.HeaderRow = p.headerRowFor(t)
+		if info.headerColumns, err = p.resolveHeaderColumns(t, info.HeaderRow); err != nil {
+			return err
+		}
+		*info.stat = SheetStat{Name: t.Name, CodeCol: info.CodeCol, ImageCol: info.ImageCol}
+		p.sheets[t.Name] = info
+
+		rowsByCode, err := p.readCodes(ctx, info)
+		if err != nil {
+			return err
+		}
+		for code, rows := range rowsByCode {
+			for _, row := range rows {
+				p.productMap[code] = append(p.productMap[code], RowRef{Sheet: t.Name, Row: row})
+			}
+			info.stat.Rows += len(rows)
+		}
+		p.Duplicates = append(p.Duplicates, findDuplicates(t.Name, rowsByCode)...)
+	}
+	return nil
+}
+
+// readCodes reads the product codes of one sheet using the streaming iterator
+func (p *Processor) readCodes(ctx context.Context, info *sheetInfo) (map[string][]int, error) {
+	rows, err := p.f.Rows(info.Name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get rows of sheet '%s': %w", info.Name, err)
+	}
+	defer rows.Close()
+
+	codeColIdx, err := excelize.ColumnNameToNumber(info.CodeCol)
+	if err != nil {
+		return nil, fmt.Errorf("invalid code column: %w", err)
+	}
+	codeColIdx-- // 0-indexed
+	pathColIdx := -1
+	if p.PathCol != "" {
+		if pathColIdx, err = excelize.ColumnNameToNumber(p.PathCol); err != nil {
+			return nil, fmt.Errorf("invalid path column: %w", err)
+		}
+		pathColIdx--
+	}
+
+	rowsByCode := make(map[string][]int)
+	rowIdx := 0
+	for rows.Next() {
+		// Check for cancellation during row processing
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		default:
+		}
+
+		rowIdx++
+		if rowIdx <= info.HeaderRow {
+			continue // Header rows never hold product codes
+		}
+		row, err := rows.Columns()
+		if err != nil {
+			log.Printf("Warning: failed to read columns for row %d of sheet '%s': %v", rowIdx, info.Name, err)
+			continue
+		}
+		info.lastCol = max(info.lastCol, len(row))
+		if len(row) > codeColIdx {
+			code := strings.TrimSpace(row[codeColIdx])
+			if code != "" {
+				rowsByCode[code] = append(rowsByCode[code], rowIdx)
+				if pathColIdx >= 0 && len(row) > pathColIdx {
+					if path := strings.TrimSpace(row[pathColIdx]); path != "" {
+						p.rowPaths[RowRef{Sheet: info.Name, Row: rowIdx}] = path
+					}
+				}
+			}
+		}
+	}
+
+	if err := rows.Error(); err != nil {
+		return nil, fmt.Errorf("error reading rows of sheet '%s': %w", info.Name, err)
+	}
+	return rowsByCode, nil
+}
+
+// writeImageHeaders writes the image column header on sheets where it was missing
+func (p *Processor) writeImageHeaders() error {
+	for _, stat := range p.SheetStats {
+		info := p.sheets[stat.Name]
+		if !info.CreateImage {
+			continue
+		}
+		cell, err := excelize.JoinCellName(info.ImageCol, info.HeaderRow)
+		if err != nil {
+			return fmt.Errorf("invalid image column: %w", err)
+		}
+		if err := p.f.SetCellValue(info.Name, cell, info.ImageHeader); err != nil {
+			return fmt.Errorf("failed to write image column header: %w", err)
+		}
+	}
+	return nil
+}
+
+// findDuplicates lists the codes of a sheet mapped to more than one row, sorted by code
+func findDuplicates(sheet string, rowsByCode map[string][]int) []Duplicate {
+	var duplicates []Duplicate
+	for code, rows := range rowsByCode {
+		if len(rows) > 1 {
+			duplicates = append(duplicates, Duplicate{Sheet: sheet, Code: code, Rows: rows})
+		}
+	}
+	sort.Slice(duplicates, func(i, j int) bool { return duplicates[i].Code < duplicates[j].Code })
+	return duplicates
+}
@@ -0,0 +1,153 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+// createMultiSheetExcel writes one sheet per entry with codes in the given column
+func createMultiSheetExcel(t *testing.T, path string, sheets []SheetTarget, codes map[string][]string) {
+	t.Helper()
+	f := excelize.NewFile()
+	defer f.Close()
+	for i, s := range sheets {
+		if i == 0 {
+			_ = f.SetSheetName("Sheet1", s.Name)
+		} else if _, err := f.NewSheet(s.Name); err != nil {
+			t.Fatal(err)
+		}
+		for row, code := range codes[s.Name] {
+			cell, _ := excelize.JoinCellName(s.CodeCol, row+1)
+			_ = f.SetCellValue(s.Name, cell, code)
+		}
+	}
+	if err := f.SaveAs(path); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestProcessor_RunMultipleSheets(t *testing.T) {
+	tempDir := t.TempDir()
+	excelPath := filepath.Join(tempDir, "test.xlsx")
+	imageDir := filepath.Join(tempDir, "images")
+	_ = os.Mkdir(imageDir, 0755)
+
+	layout := []SheetTarget{
+		{Name: "BrandA", CodeCol: "A"},
+		{Name: "BrandB", CodeCol: "C"},
+		{Name: "Notes", CodeCol: "A"},
+	}
+	createMultiSheetExcel(t, excelPath, layout, map[string][]string{
+		"BrandA": {"P001", "P002"},
+		"BrandB": {"P001", "P003", "P004"},
+		"Notes":  {"P002"},
+	})
+	for _, name := range []string{"P001.png", "P002.png", "P003.png"} {
+		_ = createDummyImage(filepath.Join(imageDir, name), 10, 10)
+	}
+
+	p := NewProcessor(excelPath, imageDir, "A", "B", "", 2, 100, 20)
+	p.Sheets = []SheetTarget{
+		{Name: "BrandA"},
+		{Name: "BrandB", CodeCol: "C", ImageCol: "D"},
+	}
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	want := []SheetStat{
+		{Name: "BrandA", CodeCol: "A", ImageCol: "B", Rows: 2, Processed: 2, Missing: 0},
+		{Name: "BrandB", CodeCol: "C", ImageCol: "D", Rows: 3, Processed: 2, Missing: 1},
+	}
+	if len(p.SheetStats) != len(want) {
+		t.Fatalf("expected %d sheet stats, got %+v", len(want), p.SheetStats)
+	}
+	for i := range want {
+		if p.SheetStats[i] != want[i] {
+			t.Errorf("sheet stat %d = %+v, want %+v", i, p.SheetStats[i], want[i])
+		}
+	}
+	if p.ProcessedCount != 4 || len(p.MissingCodes) != 1 || p.MissingCodes[0] != "P004" {
+		t.Errorf("unexpected totals: processed %d, missing %v", p.ProcessedCount, p.MissingCodes)
+	}
+
+	f, err := excelize.OpenFile(p.OutputPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+	for _, c := range []struct{ sheet, cell string }{{"BrandA", "B1"}, {"BrandA", "B2"}, {"BrandB", "D1"}, {"BrandB", "D2"}} {
+		if pics, _ := f.GetPictures(c.sheet, c.cell); len(pics) != 1 {
+			t.Errorf("expected a picture in %s!%s, got %d", c.sheet, c.cell, len(pics))
+		}
+	}
+	if cells, _ := f.GetPictureCells("Notes"); len(cells) != 0 {
+		t.Errorf("expected no pictures on the untargeted sheet, got %v", cells)
+	}
+}
+
+func TestProcessor_SheetTargets(t *testing.T) {
+	excelPath := filepath.Join(t.TempDir(), "test.xlsx")
+	createMultiSheetExcel(t, excelPath, []SheetTarget{{Name: "One", CodeCol: "A"}, {Name: "Two", CodeCol: "A"}}, nil)
+	f, err := excelize.OpenFile(excelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	// A chart sheet is listed with the worksheets but has no cells
+	if err := f.AddChartSheet("Chart", &excelize.Chart{
+		Type:   excelize.Col,
+		Series: []excelize.ChartSeries{{Name: "One!$A$1", Values: "One!$A$1:$A$2"}},
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if err := f.Save(); err != nil {
+		t.Fatal(err)
+	}
+	f.Close()
+	if f, err = excelize.OpenFile(excelPath); err != nil {
+		t.Fatal(err)
+	}
+	defer f.Close()
+
+	types, err := sheetTypes(excelPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := map[string]string{"One": "worksheet", "Two": "worksheet", "Chart": "chartsheet"}; !reflect.DeepEqual(types, want) {
+		t.Errorf("sheetTypes() = %v, want %v", types, want)
+	}
+
+	p := &Processor{f: f, ExcelPath: excelPath, CodeCol: "A", ImageCol: "F", AllSheets: true,
+		Sheets: []SheetTarget{{Name: "Two", CodeHeader: "SKU"}}}
+	targets, err := p.sheetTargets()
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []SheetTarget{
+		{Name: "One", CodeCol: "A", ImageCol: "F"},
+		{Name: "Two", CodeHeader: "SKU", ImageCol: "F"},
+	}
+	if len(targets) != 2 || targets[0] != want[0] || targets[1] != want[1] {
+		t.Errorf("sheetTargets() = %+v, want %+v", targets, want)
+	}
+
+	p = &Processor{f: f, ExcelPath: excelPath, Sheets: []SheetTarget{{Name: "Missing"}}}
+	if _, err := p.sheetTargets(); err == nil {
+		t.Error("expected an error for an unknown sheet")
+	}
+	p = &Processor{f: f, ExcelPath: excelPath, Sheets: []SheetTarget{{Name: "Chart"}}}
+	if _, err := p.sheetTargets(); err == nil || !strings.Contains(err.Error(), "not a worksheet") {
+		t.Errorf("expected an error for a chart sheet, got %v", err)
+	}
+
+	p = &Processor{f: f, ExcelPath: excelPath}
+	if targets, _ := p.sheetTargets(); len(targets) != 1 || targets[0].Name != "One" {
+		t.Errorf("expected the first sheet by default, got %+v", targets)
+	}
+}
@@ -0,0 +1,157 @@
+package engine
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"image"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Reasons an image file is rejected
+const (
+	ReasonUnreadable = "unreadable"      // The file cannot be opened or read
+	ReasonCorrupt    = "corrupt"         // The file is not a valid or complete image
+	ReasonFileSize   = "file_too_large"  // The file exceeds ValidateOptions.MaxFileBytes
+	ReasonPixels     = "too_many_pixels" // The image exceeds Processor.MaxPixels
+)
+
+// ValidateOptions guards against broken and oversized image files
+type ValidateOptions struct {
+	FullDecode     bool   // Decode every image completely, even when the original is embedded
+	MaxFileBytes   int64  // Larger files are rejected, 0 = no limit
+	QuarantineDir  string // Rejected files are listed in this folder with the reason
+	QuarantineMove bool   // Also move rejected files into QuarantineDir
+}
+
+// ImageError is returned for an image file that failed validation
+type ImageError struct {
+	Reason string
+	Err    error
+}
+
+func (e *ImageError) Error() string { return e.Reason + ": " + e.Err.Error() }
+func (e *ImageError) Unwrap() error { return e.Err }
+
+// Quarantined records a rejected image file
+type Quarantined struct {
+	File   string `json:"file"` // Path relative to the image directory
+	Reason string `json:"reason"`
+	Error  string `json:"error"`
+	Moved  bool   `json:"moved"`
+}
+
+// maxPixels returns the pixel limit, defaulting to DefaultMaxPixels
+func (p *Processor) maxPixels() int {
+	if p.MaxPixels <= 0 {
+		return DefaultMaxPixels
+	}
+	return p.MaxPixels
+}
+
+// checkFileSize rejects files above the configured size limit before they are read
+func (p *Processor) checkFileSize(path string) error {
+	if p.Validate.MaxFileBytes <= 0 {
+		return nil
+	}
+	info, err := os.Stat(path)
+	if err != nil {
+		return &ImageError{Reason: ReasonUnreadable, Err: err}
+	}
+	if info.Size() > p.Validate.MaxFileBytes {
+		return &ImageError{Reason: ReasonFileSize, Err: fmt.Errorf("%d bytes exceeds the limit of %d", info.Size(), p.Validate.MaxFileBytes)}
+	}
+	return nil
+}
+
+// checkImage classifies a load error and rejects images above the pixel
+// limit before they are decoded
+func (p *Processor) checkImage(loadErr error, width, height int) error {
+	if loadErr != nil {
+		var pathErr *fs.PathError
+		if errors.As(loadErr, &pathErr) {
+			return &ImageError{Reason: ReasonUnreadable, Err: loadErr}
+		}
+		return &ImageError{Reason: ReasonCorrupt, Err: loadErr}
+	}
+	if limit := p.maxPixels(); width*height > limit {
+		return &ImageError{Reason: ReasonPixels, Err: fmt.Errorf("%dx%d exceeds the limit of %d pixels", width, height, limit)}
+	}
+	return nil
+}
+
+// moveToQuarantine moves a rejected file into the quarantine folder, noting
+// failures in its error text
+func (p *Processor) moveToQuarantine(q *Quarantined) {
+	dst := filepath.Join(p.Validate.QuarantineDir, strings.TrimPrefix(q.File, filepath.VolumeName(q.File)))
+	err := os.MkdirAll(filepath.Dir(dst), 0755)
+	if err == nil {
+		err = os.Rename(p.imagePath(q.File), dst)
+	}
+	if err != nil {
+		q.Error += "; not moved: " + err.Error()
+		return
+	}
+	q.Moved = true
+}
+
+// fullDecode decodes data completely, catching truncated files whose header is intact
+func fullDecode(data []byte) error {
+	if _, _, err := image.Decode(bytes.NewReader(data)); err != nil {
+		return &ImageError{Reason: ReasonCorrupt, Err: err}
+	}
+	return nil
+}
+
+// quarantine lists the rejected files in the quarantine folder and moves
+// them there when configured. Moved files keep their relative path.
+func (p *Processor) quarantine(rejected map[string]*ImageError, timestamp string) error {
+	if len(rejected) == 0 {
+		return nil
+	}
+	files := make([]string, 0, len(rejected))
+	for rel := range rejected {
+		files = append(files, rel)
+	}
+	sort.Strings(files)
+
+	for _, rel := range files {
+		e := rejected[rel]
+		p.Quarantined = append(p.Quarantined, Quarantined{File: rel, Reason: e.Reason, Error: e.Err.Error()})
+	}
+	dir := p.Validate.QuarantineDir
+	if dir == "" {
+		return nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create quarantine folder: %w", err)
+	}
+
+	for i := range p.Quarantined {
+		if p.Validate.QuarantineMove {
+			p.moveToQuarantine(&p.Quarantined[i])
+		}
+	}
+
+	listPath := filepath.Join(dir, fmt.Sprintf("quarantine_%s.csv", timestamp))
+	file, err := os.Create(listPath)
+	if err != nil {
+		return fmt.Errorf("failed to write quarantine list: %w", err)
+	}
+	defer file.Close()
+	w := csv.NewWriter(file)
+	_ = w.Write([]string{"file", "reason", "error", "moved"})
+	for _, q := range p.Quarantined {
+		_ = w.Write([]string{q.File, q.Reason, q.Error, fmt.Sprint(q.Moved)})
+	}
+	w.Flush()
+	if err := w.Error(); err != nil {
+		return fmt.Errorf("failed to write quarantine list: %w", err)
+	}
+	return file.Close()
+}
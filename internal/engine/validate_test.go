@@ -0,0 +1,140 @@
+package engine
+
+import (
+	"context"
+	"encoding/csv"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestProcessor_RunValidation(t *testing.T) {
+	jpg := encodeTestImage(t, FormatJPEG, 256, 256)
+	truncated := jpg[:len(jpg)*2/3] // Header intact, scan data cut off
+
+	tests := []struct {
+		name       string
+		setup      func(p *Processor)
+		want       []Quarantined // Without error text
+		wantInsert int
+	}{
+		{
+			name:       "truncated file kept without full decode",
+			setup:      func(p *Processor) { p.Resample.KeepOriginal = true },
+			wantInsert: 3,
+		},
+		{
+			name: "full decode",
+			setup: func(p *Processor) {
+				p.Resample.KeepOriginal = true
+				p.Validate.FullDecode = true
+			},
+			want:       []Quarantined{{File: "P002.jpg", Reason: ReasonCorrupt}},
+			wantInsert: 2,
+		},
+		{
+			name:       "resampling always decodes",
+			setup:      func(p *Processor) {},
+			want:       []Quarantined{{File: "P002.jpg", Reason: ReasonCorrupt}},
+			wantInsert: 2,
+		},
+		{
+			name: "file size limit",
+			setup: func(p *Processor) {
+				p.Resample.KeepOriginal = true
+				p.Validate.MaxFileBytes = int64(len(truncated))
+			},
+			want:       []Quarantined{{File: "P001.jpg", Reason: ReasonFileSize}},
+			wantInsert: 2,
+		},
+		{
+			name:       "pixel limit",
+			setup:      func(p *Processor) { p.MaxPixels = 256 * 256 },
+			want:       []Quarantined{{File: "P002.jpg", Reason: ReasonCorrupt}, {File: "P003.png", Reason: ReasonPixels}},
+			wantInsert: 1,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			excelPath := filepath.Join(tempDir, "test.xlsx")
+			imageDir := filepath.Join(tempDir, "images")
+			_ = os.Mkdir(imageDir, 0755)
+
+			if err := createDummyExcel(excelPath, "Sheet1", "A", []string{"P001", "P002", "P003"}); err != nil {
+				t.Fatal(err)
+			}
+			_ = os.WriteFile(filepath.Join(imageDir, "P001.jpg"), jpg, 0644)
+			_ = os.WriteFile(filepath.Join(imageDir, "P002.jpg"), truncated, 0644)
+			_ = createDummyImage(filepath.Join(imageDir, "P003.png"), 300, 300)
+
+			p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 2, 100, 20)
+			tt.setup(p)
+			if err := p.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+
+			var got []Quarantined
+			for _, q := range p.Quarantined {
+				if q.Error == "" {
+					t.Errorf("%s has no error text", q.File)
+				}
+				got = append(got, Quarantined{File: q.File, Reason: q.Reason})
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("quarantined = %+v, want %+v", got, tt.want)
+			}
+			if p.ProcessedCount != tt.wantInsert {
+				t.Errorf("inserted %d pictures, want %d", p.ProcessedCount, tt.wantInsert)
+			}
+		})
+	}
+}
+
+func TestProcessor_RunQuarantineFolder(t *testing.T) {
+	tempDir := t.TempDir()
+	excelPath := filepath.Join(tempDir, "test.xlsx")
+	imageDir := filepath.Join(tempDir, "images")
+	quarantineDir := filepath.Join(tempDir, "quarantine")
+	_ = os.MkdirAll(filepath.Join(imageDir, "sub"), 0755)
+
+	if err := createDummyExcel(excelPath, "Sheet1", "A", []string{"P001", "P002"}); err != nil {
+		t.Fatal(err)
+	}
+	_ = createDummyImage(filepath.Join(imageDir, "P001.png"), 10, 10)
+	_ = os.WriteFile(filepath.Join(imageDir, "sub", "P002.png"), []byte("broken"), 0644)
+
+	p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 2, 100, 20)
+	p.Scan.Recursive = true
+	p.Validate.QuarantineDir = quarantineDir
+	p.Validate.QuarantineMove = true
+	if err := p.Run(context.Background()); err != nil {
+		t.Fatalf("Run() error: %v", err)
+	}
+
+	if len(p.Quarantined) != 1 || !p.Quarantined[0].Moved || p.Quarantined[0].Reason != ReasonCorrupt {
+		t.Fatalf("unexpected quarantine: %+v", p.Quarantined)
+	}
+	if _, err := os.Stat(filepath.Join(quarantineDir, "sub", "P002.png")); err != nil {
+		t.Errorf("file not moved into quarantine: %v", err)
+	}
+	if _, err := os.Stat(filepath.Join(imageDir, "sub", "P002.png")); !os.IsNotExist(err) {
+		t.Error("file still in the image folder")
+	}
+
+	lists, _ := filepath.Glob(filepath.Join(quarantineDir, "quarantine_*.csv"))
+	if len(lists) != 1 {
+		t.Fatalf("expected one quarantine list, got %v", lists)
+	}
+	file, err := os.Open(lists[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer file.Close()
+	records, err := csv.NewReader(file).ReadAll()
+	if err != nil || len(records) != 2 || records[1][0] != filepath.Join("sub", "P002.png") || records[1][1] != ReasonCorrupt {
+		t.Errorf("unexpected quarantine list: %v (err: %v)", records, err)
+	}
+}
@@ -0,0 +1,93 @@
+package engine
+
+import (
+	"errors"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Variant layouts for VariantOptions.Layout
+const (
+	LayoutAcross = "across" // One image per cell, left to right from ImageCol (default)
+	LayoutStack  = "stack"  // All images inside the ImageCol cell, top to bottom
+)
+
+// Variant orderings for VariantOptions.Order
+const (
+	OrderSuffix = "suffix" // Base image first, then by variant number (default)
+	OrderName   = "name"   // Alphabetical by file path
+)
+
+// variantPlaceholder marks the position of the variant number in VariantOptions.Suffix
+const variantPlaceholder = "{n}"
+
+// VariantOptions groups several image files under one product code,
+// e.g. P001.jpg, P001_2.jpg and P001_3.jpg with Suffix "_{n}"
+type VariantOptions struct {
+	Suffix    string // Variant suffix pattern, e.g. "_{n}" or "-{n}". Empty disables variants
+	MaxImages int    // Maximum images per code, 0 = unlimited
+	Layout    string // LayoutAcross or LayoutStack
+	Order     string // OrderSuffix or OrderName
+}
+
+// validate checks the variant options
+func (v VariantOptions) validate() error {
+	if v.Suffix != "" && !strings.Contains(v.Suffix, variantPlaceholder) {
+		return errors.New("variant suffix must contain " + variantPlaceholder)
+	}
+	switch v.Layout {
+	case "", LayoutAcross, LayoutStack:
+	default:
+		return errors.New("invalid variant layout '" + v.Layout + "'")
+	}
+	switch v.Order {
+	case "", OrderSuffix, OrderName:
+	default:
+		return errors.New("invalid variant order '" + v.Order + "'")
+	}
+	return nil
+}
+
+// split separates a filename stem into its base code and variant number.
+// Stems without a variant suffix are returned unchanged with variant 0.
+func (v VariantOptions) split(stem string) (base string, variant int) {
+	if v.Suffix == "" {
+		return stem, 0
+	}
+	before, after, _ := strings.Cut(v.Suffix, variantPlaceholder)
+	rest, ok := strings.CutSuffix(stem, after)
+	if !ok {
+		return stem, 0
+	}
+	digits := len(rest)
+	for digits > 0 && rest[digits-1] >= '0' && rest[digits-1] <= '9' {
+		digits--
+	}
+	if digits == len(rest) {
+		return stem, 0
+	}
+	n, err := strconv.Atoi(rest[digits:])
+	if err != nil {
+		return stem, 0
+	}
+	base, ok = strings.CutSuffix(rest[:digits], before)
+	if !ok || base == "" {
+		return stem, 0
+	}
+	return base, n
+}
+
+// arrange orders the files of one group and applies the image limit
+func (v VariantOptions) arrange(files []imageFile) []imageFile {
+	sort.SliceStable(files, func(i, j int) bool {
+		if v.Order != OrderName && files[i].Variant != files[j].Variant {
+			return files[i].Variant < files[j].Variant
+		}
+		return files[i].Rel < files[j].Rel
+	})
+	if v.MaxImages > 0 && len(files) > v.MaxImages {
+		files = files[:v.MaxImages]
+	}
+	return files
+}
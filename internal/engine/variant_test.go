@@ -0,0 +1,144 @@
+package engine
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/xuri/excelize/v2"
+)
+
+func TestVariantOptions_Split(t *testing.T) {
+	tests := []struct {
+		suffix      string
+		stem        string
+		wantBase    string
+		wantVariant int
+	}{
+		{"", "P001_2", "P001_2", 0},
+		{"_{n}", "P001", "P001", 0},
+		{"_{n}", "P001_2", "P001", 2},
+		{"_{n}", "P001_12", "P001", 12},
+		{"_{n}", "P001_", "P001_", 0},
+		{"_{n}", "_2", "_2", 0},
+		{"-{n}", "P001_2", "P001_2", 0},
+		{" ({n})", "P001 (3)", "P001", 3},
+		{"_v{n}_web", "P001_v2_web", "P001", 2},
+	}
+
+	for _, tt := range tests {
+		v := VariantOptions{Suffix: tt.suffix}
+		base, variant := v.split(tt.stem)
+		if base != tt.wantBase || variant != tt.wantVariant {
+			t.Errorf("split(%q) with %q = %q, %d; want %q, %d", tt.stem, tt.suffix, base, variant, tt.wantBase, tt.wantVariant)
+		}
+	}
+}
+
+func TestVariantOptions_Validate(t *testing.T) {
+	invalid := []VariantOptions{
+		{Suffix: "_2"},
+		{Suffix: "_{n}", Layout: "diagonal"},
+		{Suffix: "_{n}", Order: "random"},
+	}
+	for _, v := range invalid {
+		if err := v.validate(); err == nil {
+			t.Errorf("validate(%+v): expected error", v)
+		}
+	}
+}
+
+func TestVariantOptions_Arrange(t *testing.T) {
+	files := []imageFile{
+		{Rel: "P001_3.jpg", Variant: 3},
+		{Rel: "P001_back.jpg", Variant: 0},
+		{Rel: "P001_10.jpg", Variant: 10},
+		{Rel: "P001_2.jpg", Variant: 2},
+	}
+
+	bySuffix := VariantOptions{MaxImages: 3}.arrange(append([]imageFile{}, files...))
+	want := []string{"P001_back.jpg", "P001_2.jpg", "P001_3.jpg"}
+	for i, f := range bySuffix {
+		if f.Rel != want[i] {
+			t.Errorf("suffix order [%d] = %s, want %s", i, f.Rel, want[i])
+		}
+	}
+
+	byName := VariantOptions{Order: OrderName}.arrange(append([]imageFile{}, files...))
+	want = []string{"P001_10.jpg", "P001_2.jpg", "P001_3.jpg", "P001_back.jpg"}
+	for i, f := range byName {
+		if f.Rel != want[i] {
+			t.Errorf("name order [%d] = %s, want %s", i, f.Rel, want[i])
+		}
+	}
+}
+
+func TestProcessor_RunVariants(t *testing.T) {
+	tests := []struct {
+		name      string
+		layout    string
+		wantCells []string
+	}{
+		{"across", LayoutAcross, []string{"B1", "C1", "D1", "B2"}},
+		{"stack", LayoutStack, []string{"B1", "B2"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			excelPath := filepath.Join(tempDir, "test.xlsx")
+			imageDir := filepath.Join(tempDir, "images")
+			_ = os.Mkdir(imageDir, 0755)
+
+			if err := createDummyExcel(excelPath, "Sheet1", "A", []string{"P001", "ABC_2"}); err != nil {
+				t.Fatal(err)
+			}
+			for _, name := range []string{"P001.png", "P001_2.png", "P001_3.png", "P001_4.png", "ABC_2.png"} {
+				_ = createDummyImage(filepath.Join(imageDir, name), 20, 10)
+			}
+
+			p := NewProcessor(excelPath, imageDir, "A", "B", "Sheet1", 2, 100, 20)
+			p.Variants = VariantOptions{Suffix: "_{n}", MaxImages: 3, Layout: tt.layout}
+			if err := p.Run(context.Background()); err != nil {
+				t.Fatalf("Run() error: %v", err)
+			}
+
+			if p.ProcessedCount != 4 {
+				t.Errorf("expected 4 processed images, got %d", p.ProcessedCount)
+			}
+			if len(p.MissingCodes) != 0 {
+				t.Errorf("expected no missing codes, got %v", p.MissingCodes)
+			}
+			if len(p.Matches) == 0 || len(p.Matches[1].Variants) != 2 {
+				t.Errorf("expected P001 to have 2 variants, got %+v", p.Matches)
+			}
+
+			f, err := excelize.OpenFile(p.OutputPath)
+			if err != nil {
+				t.Fatal(err)
+			}
+			defer f.Close()
+
+			cells, err := f.GetPictureCells("Sheet1")
+			if err != nil {
+				t.Fatal(err)
+			}
+			got := make(map[string]int)
+			for _, cell := range cells {
+				pics, _ := f.GetPictures("Sheet1", cell)
+				got[cell] = len(pics)
+			}
+			total := 0
+			for _, cell := range tt.wantCells {
+				if got[cell] == 0 {
+					t.Errorf("expected a picture in %s, got %v", cell, got)
+				}
+				total += got[cell]
+			}
+			if total != 4 {
+				t.Errorf("expected 4 pictures in %v, got %v", tt.wantCells, got)
+			}
+		})
+	}
+}
@@ -1,7 +1,11 @@
 package main
 
 import (
+	"context"
 	"embed"
+	"log"
+	"os"
+	"os/signal"
 
 	"github.com/wailsapp/wails/v2"
 	"github.com/wailsapp/wails/v2/pkg/options"
@@ -13,6 +17,16 @@ import (
 var assets embed.FS
 
 func main() {
+	// Headless mode: tool_chen_anh import --excel x.xlsx --images dir ...
+	if len(os.Args) > 1 && os.Args[1] == CLICommand {
+		attachConsole()
+		log.SetOutput(os.Stderr) // The log package keeps the stream it started with
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		code := runCLI(ctx, os.Args[2:], os.Stdout, os.Stderr)
+		stop()
+		os.Exit(code)
+	}
+
 	// Create an instance of the app structure
 	app := NewApp()
 